@@ -0,0 +1,129 @@
+package gcpmetrics
+
+import "sync/atomic"
+
+// boundSeries caches the registry entry for a fixed label value combination.
+// If the entry is deleted from the registry, the next access re-resolves it,
+// so a bound handle remains valid for the lifetime of its dynamic metric.
+type boundSeries[T any] struct {
+	registry    *LabelRegistry[T]
+	labelValues []string
+	entry       atomic.Pointer[labelEntry[T]]
+}
+
+// bind resolves the entry for labelValues. A nil registry produces a no-op handle.
+func (b *boundSeries[T]) bind(registry *LabelRegistry[T], labelValues []string) {
+	if registry == nil {
+		return
+	}
+	b.registry = registry
+	// Copy the label values so the caller may reuse its slice
+	b.labelValues = append([]string(nil), labelValues...)
+	b.entry.Store(registry.getEntry(b.labelValues))
+}
+
+// get returns the bound metric instance, or false if the handle is a no-op.
+func (b *boundSeries[T]) get() (T, bool) {
+	if b.registry == nil {
+		var zero T
+		return zero, false
+	}
+	entry := b.entry.Load()
+	if entry.removed.Load() {
+		entry = b.registry.getEntry(b.labelValues)
+		b.entry.Store(entry)
+	}
+	return entry.value, true
+}
+
+// BoundCounter is a DynamicCounter handle bound to a fixed label value combination.
+// It skips the label registry lookup on every update and implements the Counter interface.
+type BoundCounter struct {
+	boundSeries[*StaticCounter]
+}
+
+// With returns a handle bound to the given label values. Handles may be cached and shared between goroutines.
+// Label arity is checked once, when the handle is created; in strict-error mode a mismatch yields a no-op handle.
+func (dc *DynamicCounter) With(labelValues ...string) *BoundCounter {
+	bc := &BoundCounter{}
+	if dc.check(labelValues) {
+		bc.bind(dc.registry, labelValues)
+	}
+	return bc
+}
+
+// Inc increments the counter by 1. The labelValues parameter is ignored for bound counters.
+func (bc *BoundCounter) Inc(labelValues ...string) {
+	if c, ok := bc.get(); ok {
+		c.Inc()
+	}
+}
+
+// Add increments the counter by n. The labelValues parameter is ignored for bound counters.
+func (bc *BoundCounter) Add(n int64, labelValues ...string) {
+	if c, ok := bc.get(); ok {
+		c.Add(n)
+	}
+}
+
+// Value returns the current counter value.
+func (bc *BoundCounter) Value() int64 {
+	if c, ok := bc.get(); ok {
+		return c.Value()
+	}
+	return 0
+}
+
+// BoundGauge is a DynamicGauge handle bound to a fixed label value combination.
+// It skips the label registry lookup on every update and implements the Gauge interface.
+type BoundGauge struct {
+	boundSeries[*StaticGauge]
+}
+
+// With returns a handle bound to the given label values. Handles may be cached and shared between goroutines.
+// Label arity is checked once, when the handle is created; in strict-error mode a mismatch yields a no-op handle.
+func (dg *DynamicGauge) With(labelValues ...string) *BoundGauge {
+	bg := &BoundGauge{}
+	if dg.check(labelValues) {
+		bg.bind(dg.registry, labelValues)
+	}
+	return bg
+}
+
+// Set sets the gauge value. The labelValues parameter is ignored for bound gauges.
+func (bg *BoundGauge) Set(n int64, labelValues ...string) {
+	if g, ok := bg.get(); ok {
+		g.Set(n)
+	}
+}
+
+// Value returns the current gauge value.
+func (bg *BoundGauge) Value() int64 {
+	if g, ok := bg.get(); ok {
+		return g.Value()
+	}
+	return 0
+}
+
+// BoundDistribution is a DynamicDistribution handle bound to a fixed label value combination.
+// It skips the label registry lookup on every update and implements the Distribution interface.
+type BoundDistribution struct {
+	boundSeries[*StaticDistribution]
+}
+
+// With returns a handle bound to the given label values. Handles may be cached and shared between goroutines.
+// Label arity is checked once, when the handle is created; in strict-error mode a mismatch yields a no-op handle.
+func (dd *DynamicDistribution) With(labelValues ...string) *BoundDistribution {
+	bd := &BoundDistribution{}
+	if dd.check(labelValues) {
+		bd.bind(dd.registry, labelValues)
+	}
+	return bd
+}
+
+// Update records a value in the distribution. The labelValues parameter is ignored for bound distributions.
+func (bd *BoundDistribution) Update(value int64, labelValues ...string) {
+	if d, ok := bd.get(); ok {
+		d.Update(value)
+	}
+}
@@ -0,0 +1,57 @@
+// Package clock provides a Clock abstraction over the time package, so that
+// emission schedules and timestamps can be tested deterministically.
+package clock
+
+import "time"
+
+// Clock is the source of time used by emitters and schedulers.
+type Clock interface {
+	// Now returns the current time.
+	Now() time.Time
+	// NewTimer creates a Timer that fires once after duration d.
+	NewTimer(d time.Duration) Timer
+}
+
+// Timer is the subset of *time.Timer used by this library.
+type Timer interface {
+	// C returns the channel on which the time is delivered when the timer fires.
+	C() <-chan time.Time
+	// Reset changes the timer to expire after duration d.
+	Reset(d time.Duration) bool
+	// Stop prevents the timer from firing.
+	Stop() bool
+}
+
+// System is the Clock backed by the time package.
+var System Clock = systemClock{}
+
+// OrSystem returns c, or System if c is nil.
+func OrSystem(c Clock) Clock {
+	if c == nil {
+		return System
+	}
+	return c
+}
+
+// systemClock implements Clock using the time package.
+type systemClock struct{}
+
+// Now returns time.Now().
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+// NewTimer returns a Timer backed by time.NewTimer.
+func (systemClock) NewTimer(d time.Duration) Timer {
+	return systemTimer{time.NewTimer(d)}
+}
+
+// systemTimer adapts *time.Timer to the Timer interface.
+type systemTimer struct {
+	*time.Timer
+}
+
+// C returns the timer's channel.
+func (t systemTimer) C() <-chan time.Time {
+	return t.Timer.C
+}
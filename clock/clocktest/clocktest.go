@@ -0,0 +1,134 @@
+// Package clocktest provides a manually advanced clock.Clock for tests.
+package clocktest
+
+import (
+	"sync"
+	"time"
+
+	"github.com/nikolaybotev/go-gcp-metrics/clock"
+)
+
+// FakeClock is a clock.Clock whose time only moves when Advance or Set is called.
+// Timers fire synchronously from Advance and Set once their deadline is reached.
+type FakeClock struct {
+	mu      sync.Mutex
+	cond    *sync.Cond
+	now     time.Time
+	timers  map[*fakeTimer]struct{} // Armed timers
+	created int                     // Number of timers ever created
+}
+
+// NewFakeClock creates a FakeClock set to the given time.
+func NewFakeClock(now time.Time) *FakeClock {
+	c := &FakeClock{
+		now:    now,
+		timers: make(map[*fakeTimer]struct{}),
+	}
+	c.cond = sync.NewCond(&c.mu)
+	return c
+}
+
+// Now returns the fake current time.
+func (c *FakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+// NewTimer creates a timer that fires once the fake time has advanced by d.
+func (c *FakeClock) NewTimer(d time.Duration) clock.Timer {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	t := &fakeTimer{clock: c, c: make(chan time.Time, 1)}
+	c.created++
+	c.armLocked(t, d)
+	return t
+}
+
+// Advance moves the fake time forward by d, firing any timers that expire.
+func (c *FakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.setLocked(c.now.Add(d))
+}
+
+// Set moves the fake time to now, firing any timers that expire.
+func (c *FakeClock) Set(now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.setLocked(now)
+}
+
+// WaitForTimers blocks until at least n timers are armed.
+// Use it to make sure the code under test is waiting before advancing the clock.
+func (c *FakeClock) WaitForTimers(n int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for len(c.timers) < n {
+		c.cond.Wait()
+	}
+}
+
+// TimersCreated returns the number of timers created by NewTimer.
+func (c *FakeClock) TimersCreated() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.created
+}
+
+func (c *FakeClock) setLocked(now time.Time) {
+	c.now = now
+	for t := range c.timers {
+		if !t.deadline.After(now) {
+			delete(c.timers, t)
+			select {
+			case t.c <- t.deadline:
+			default:
+			}
+		}
+	}
+}
+
+func (c *FakeClock) armLocked(t *fakeTimer, d time.Duration) {
+	t.deadline = c.now.Add(d)
+	if d <= 0 {
+		select {
+		case t.c <- t.deadline:
+		default:
+		}
+		return
+	}
+	c.timers[t] = struct{}{}
+	c.cond.Broadcast()
+}
+
+// fakeTimer is a clock.Timer driven by a FakeClock.
+type fakeTimer struct {
+	clock    *FakeClock
+	c        chan time.Time
+	deadline time.Time // Guarded by clock.mu
+}
+
+// C returns the channel on which the fake time is delivered when the timer fires.
+func (t *fakeTimer) C() <-chan time.Time {
+	return t.c
+}
+
+// Reset re-arms the timer to fire after d of fake time.
+func (t *fakeTimer) Reset(d time.Duration) bool {
+	t.clock.mu.Lock()
+	defer t.clock.mu.Unlock()
+	_, active := t.clock.timers[t]
+	delete(t.clock.timers, t)
+	t.clock.armLocked(t, d)
+	return active
+}
+
+// Stop disarms the timer.
+func (t *fakeTimer) Stop() bool {
+	t.clock.mu.Lock()
+	defer t.clock.mu.Unlock()
+	_, active := t.clock.timers[t]
+	delete(t.clock.timers, t)
+	return active
+}
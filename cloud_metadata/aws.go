@@ -1,42 +1,69 @@
 package cloud_metadata
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"io"
 	"net/http"
+	"strconv"
 	"time"
 )
 
 const (
 	imdsBaseURL   = "http://169.254.169.254"
 	imdsTokenPath = "/latest/api/token"
+	// defaultIMDSTokenTTL is the lifetime requested for IMDSv2 tokens by default.
+	defaultIMDSTokenTTL = 6 * time.Hour
+	// maxIMDSTokenTTL is the longest lifetime the IMDS accepts.
+	maxIMDSTokenTTL = 6 * time.Hour
+	// maxIMDSTokenRefreshMargin is how long before expiry a token is replaced, to allow for request latency.
+	// Short-lived tokens are replaced after 90% of their lifetime.
+	maxIMDSTokenRefreshMargin = time.Minute
+	// imdsTokenRetryBackoff is how long a failed token request is remembered. With a hop limit of 1,
+	// token requests from containers time out, and every lookup would otherwise wait for a new one.
+	imdsTokenRetryBackoff = 30 * time.Second
+	// awsAutoScalingGroupTagPath is the instance tag set by EC2 Auto Scaling.
+	awsAutoScalingGroupTagPath = "/latest/meta-data/tags/instance/aws:autoscaling:groupName"
 )
 
 // GetAWSEC2InstanceID returns the EC2 instance ID from IMDSv2
-func GetAWSEC2InstanceID() (string, error) {
-	return GetAWSMetadata("/latest/meta-data/instance-id")
+func (c *Client) GetAWSEC2InstanceID(ctx context.Context) (string, error) {
+	return c.GetAWSMetadata(ctx, "/latest/meta-data/instance-id")
 }
 
 // GetAWSAutoScalingGroupName returns the Auto Scaling Group name from IMDSv2
-// Note: Requires Instance Metadata Tags to be enabled on the instance
-func GetAWSAutoScalingGroupName() (string, error) {
-	return GetAWSMetadata("/latest/meta-data/tags/instance/aws:autoscaling:groupName")
+// Note: Requires Instance Metadata Tags to be enabled on the instance. If they are not,
+// the error is an AWSMetadataError of kind AWSTagsDisabled
+func (c *Client) GetAWSAutoScalingGroupName(ctx context.Context) (string, error) {
+	name, err := c.GetAWSMetadata(ctx, awsAutoScalingGroupTagPath)
+	var metadataErr *AWSMetadataError
+	if err == nil || !errors.As(err, &metadataErr) || metadataErr.Kind != AWSNotFound {
+		return name, err
+	}
+
+	// The tag is missing either because tags are not accessible or because the instance is not in a group
+	_, tagsErr := c.GetAWSMetadata(ctx, "/latest/meta-data/tags/instance")
+	if errors.As(tagsErr, &metadataErr) && metadataErr.Kind == AWSNotFound {
+		return "", &AWSMetadataError{Kind: AWSTagsDisabled, Path: awsAutoScalingGroupTagPath, StatusCode: http.StatusNotFound}
+	}
+	return "", &AWSMetadataError{Kind: AWSNotFound, Path: awsAutoScalingGroupTagPath, StatusCode: http.StatusNotFound,
+		Err: errors.New("the instance is not in an Auto Scaling group")}
 }
 
 // GetAWSRegion returns the AWS region from IMDSv2
-func GetAWSRegion() (string, error) {
-	return GetAWSMetadata("/latest/meta-data/placement/region")
+func (c *Client) GetAWSRegion(ctx context.Context) (string, error) {
+	return c.GetAWSMetadata(ctx, "/latest/meta-data/placement/region")
 }
 
 // GetAWSAvailabilityZone returns the AWS availability zone from IMDSv2
-func GetAWSAvailabilityZone() (string, error) {
-	return GetAWSMetadata("/latest/meta-data/placement/availability-zone")
+func (c *Client) GetAWSAvailabilityZone(ctx context.Context) (string, error) {
+	return c.GetAWSMetadata(ctx, "/latest/meta-data/placement/availability-zone")
 }
 
 // GetAWSAccountID returns the AWS account ID from IMDSv2
-func GetAWSAccountID() (string, error) {
-	data, err := GetAWSMetadata("/latest/dynamic/instance-identity/document")
+func (c *Client) GetAWSAccountID(ctx context.Context) (string, error) {
+	data, err := c.GetAWSMetadata(ctx, "/latest/dynamic/instance-identity/document")
 	if err != nil {
 		return "", err
 	}
@@ -51,59 +78,175 @@ func GetAWSAccountID() (string, error) {
 	return doc.AccountId, nil
 }
 
-// getIMDSv2Token obtains an IMDSv2 session token
-func getIMDSv2Token(client *http.Client) string {
-	tokenReq, err := http.NewRequest("PUT", imdsBaseURL+imdsTokenPath, nil)
-	if err != nil {
-		return ""
-	}
-	tokenReq.Header.Set("X-aws-ec2-metadata-token-ttl-seconds", "30")
+// tokenFetch is an IMDSv2 token request shared by concurrent callers. done is closed once token and err are set.
+type tokenFetch struct {
+	done     chan struct{}
+	token    string
+	err      error
+	canceled bool // The request failed because the context of the caller that made it ended
+}
 
-	tokenResp, err := client.Do(tokenReq)
-	if err != nil {
-		return ""
+// getIMDSv2Token obtains an IMDSv2 session token, reusing the previous token until it is about to expire.
+// Concurrent callers share a single token request. A failed request is remembered for imdsTokenRetryBackoff.
+func (c *Client) getIMDSv2Token(ctx context.Context) (string, error) {
+	for {
+		c.tokenMu.Lock()
+		now := time.Now()
+		if c.token != "" && now.Before(c.tokenExpiry) {
+			defer c.tokenMu.Unlock()
+			return c.token, nil
+		}
+		if c.tokenErr != nil && now.Before(c.tokenRetry) {
+			defer c.tokenMu.Unlock()
+			return "", c.tokenErr
+		}
+		fetch := c.tokenFetch
+		if fetch == nil {
+			fetch = &tokenFetch{done: make(chan struct{})}
+			c.tokenFetch = fetch
+			c.tokenMu.Unlock()
+			c.fetchIMDSv2Token(ctx, fetch)
+			return fetch.token, fetch.err
+		}
+		c.tokenMu.Unlock()
+
+		select {
+		case <-fetch.done:
+		case <-ctx.Done():
+			return "", fmt.Errorf("failed to get IMDSv2 token: %w", ctx.Err())
+		}
+		if !fetch.canceled {
+			return fetch.token, fetch.err
+		}
+		// The caller that made the request gave up; retry with this context
 	}
-	defer tokenResp.Body.Close()
+}
 
-	if tokenResp.StatusCode != http.StatusOK {
-		return ""
+// fetchIMDSv2Token requests a token and stores the outcome in fetch and, unless ctx ended, in the Client.
+func (c *Client) fetchIMDSv2Token(ctx context.Context, fetch *tokenFetch) {
+	header := http.Header{}
+	header.Set("X-aws-ec2-metadata-token-ttl-seconds", strconv.Itoa(int(c.awsTokenTTL.Seconds())))
+	requested := time.Now()
+	fetch.token, fetch.err = c.get(ctx, http.MethodPut, c.awsBaseURL+imdsTokenPath, header)
+	fetch.canceled = fetch.err != nil && ctx.Err() != nil
+
+	c.tokenMu.Lock()
+	defer c.tokenMu.Unlock()
+	c.tokenFetch = nil
+	switch {
+	case fetch.err == nil:
+		c.token = fetch.token
+		c.tokenExpiry = requested.Add(c.awsTokenTTL - min(c.awsTokenTTL/10, maxIMDSTokenRefreshMargin))
+		c.tokenErr = nil
+	case !fetch.canceled:
+		c.tokenErr = fetch.err
+		c.tokenRetry = time.Now().Add(imdsTokenRetryBackoff)
 	}
-	tokenBytes, err := io.ReadAll(tokenResp.Body)
-	if err != nil {
-		return ""
+	close(fetch.done)
+}
+
+// invalidateIMDSv2Token discards token if it is the current token, so that the next lookup requests a new one.
+func (c *Client) invalidateIMDSv2Token(token string) {
+	c.tokenMu.Lock()
+	defer c.tokenMu.Unlock()
+	if c.token == token {
+		c.token = ""
 	}
-	return string(tokenBytes)
 }
 
-// GetAWSMetadata fetches a metadata value from the given path using IMDSv2
-func GetAWSMetadata(path string) (string, error) {
-	client := http.Client{Timeout: 2 * time.Second}
-	defer client.CloseIdleConnections()
+// GetAWSMetadata fetches a metadata value from the given path using IMDSv2.
+// Unless AWSRequireIMDSv2 is set, it falls back to IMDSv1 when no token can be obtained.
+// Errors are of type *AWSMetadataError
+func (c *Client) GetAWSMetadata(ctx context.Context, path string) (string, error) {
+	return c.cached(ctx, c.awsBaseURL+path, func(ctx context.Context) (string, error) {
+		return c.fetchAWSMetadata(ctx, path)
+	})
+}
 
-	token := getIMDSv2Token(&client)
+// fetchAWSMetadata requests a token and the metadata value, and classifies failures.
+func (c *Client) fetchAWSMetadata(ctx context.Context, path string) (string, error) {
+	header := http.Header{}
+	token, tokenErr := c.getIMDSv2Token(ctx)
+	switch {
+	case tokenErr == nil:
+		header.Set("X-aws-ec2-metadata-token", token)
+	case ctx.Err() != nil:
+		// The caller gave up, which says nothing about the IMDS
+		return "", &AWSMetadataError{Kind: AWSRequestFailed, Path: path, Err: tokenErr}
+	case isGCPMetadataServer(tokenErr):
+		// On GCE, the link-local address is the GCP metadata server
+		return "", &AWSMetadataError{Kind: AWSUnavailable, Path: path, StatusCode: statusCode(tokenErr), Err: tokenErr}
+	case statusCode(tokenErr) == http.StatusForbidden:
+		return "", &AWSMetadataError{Kind: AWSEndpointDisabled, Path: path, StatusCode: http.StatusForbidden, Err: tokenErr}
+	case statusCode(tokenErr) == 0 && !isTimeout(tokenErr):
+		// Connection refused or no route: there is no IMDS
+		return "", &AWSMetadataError{Kind: AWSUnavailable, Path: path, Err: tokenErr}
+	case c.awsRequireIMDSv2:
+		return "", &AWSMetadataError{Kind: AWSTokenUnavailable, Path: path, StatusCode: statusCode(tokenErr), Err: tokenErr}
+	}
 
-	req, err := http.NewRequest("GET", imdsBaseURL+path, nil)
-	if err != nil {
-		return "", fmt.Errorf("failed to create request for %s: %v", path, err)
+	value, err := c.get(ctx, http.MethodGet, c.awsBaseURL+path, header)
+	if err == nil {
+		return value, nil
 	}
-	if token != "" {
-		req.Header.Set("X-aws-ec2-metadata-token", token)
+	if ctx.Err() != nil {
+		return "", &AWSMetadataError{Kind: AWSRequestFailed, Path: path, Err: err}
 	}
-
-	resp, err := client.Do(req)
-	if err != nil {
-		return "", fmt.Errorf("failed to get %s: %v", path, err)
+	if isGCPMetadataServer(err) {
+		return "", &AWSMetadataError{Kind: AWSUnavailable, Path: path, StatusCode: statusCode(err), Err: err}
 	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("failed to get %s with HTTP status %d", path, resp.StatusCode)
+	switch code := statusCode(err); code {
+	case 0:
+		if tokenErr != nil {
+			// Neither the token nor the value request was answered
+			return "", &AWSMetadataError{Kind: AWSUnavailable, Path: path, Err: err}
+		}
+		return "", &AWSMetadataError{Kind: AWSRequestFailed, Path: path, Err: err}
+	case http.StatusUnauthorized:
+		if tokenErr == nil {
+			// Request a new token on the next lookup rather than reusing a rejected one
+			c.invalidateIMDSv2Token(token)
+			tokenErr = errors.New("the token was rejected")
+		}
+		return "", &AWSMetadataError{Kind: AWSTokenRequired, Path: path, StatusCode: code, Err: tokenErr}
+	case http.StatusForbidden:
+		return "", &AWSMetadataError{Kind: AWSEndpointDisabled, Path: path, StatusCode: code, Err: err}
+	case http.StatusNotFound:
+		return "", &AWSMetadataError{Kind: AWSNotFound, Path: path, StatusCode: code}
+	default:
+		return "", &AWSMetadataError{Kind: AWSRequestFailed, Path: path, StatusCode: code, Err: err}
 	}
+}
 
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return "", fmt.Errorf("failed to read response for %s: %v", path, err)
-	}
+// GetAWSEC2InstanceID returns the EC2 instance ID from IMDSv2
+func GetAWSEC2InstanceID() (string, error) {
+	return DefaultClient().GetAWSEC2InstanceID(context.Background())
+}
 
-	return string(body), nil
+// GetAWSAutoScalingGroupName returns the Auto Scaling Group name from IMDSv2
+// Note: Requires Instance Metadata Tags to be enabled on the instance. If they are not,
+// the error is an AWSMetadataError of kind AWSTagsDisabled
+func GetAWSAutoScalingGroupName() (string, error) {
+	return DefaultClient().GetAWSAutoScalingGroupName(context.Background())
+}
+
+// GetAWSRegion returns the AWS region from IMDSv2
+func GetAWSRegion() (string, error) {
+	return DefaultClient().GetAWSRegion(context.Background())
+}
+
+// GetAWSAvailabilityZone returns the AWS availability zone from IMDSv2
+func GetAWSAvailabilityZone() (string, error) {
+	return DefaultClient().GetAWSAvailabilityZone(context.Background())
+}
+
+// GetAWSAccountID returns the AWS account ID from IMDSv2
+func GetAWSAccountID() (string, error) {
+	return DefaultClient().GetAWSAccountID(context.Background())
+}
+
+// GetAWSMetadata fetches a metadata value from the given path using IMDSv2.
+// Errors are of type *AWSMetadataError
+func GetAWSMetadata(path string) (string, error) {
+	return DefaultClient().GetAWSMetadata(context.Background(), path)
 }
@@ -0,0 +1,112 @@
+package cloud_metadata
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+)
+
+// ErrNotAWS matches, with errors.Is, an AWSMetadataError of kind AWSUnavailable.
+var ErrNotAWS = errors.New("not running on AWS EC2")
+
+// AWSMetadataErrorKind classifies an AWSMetadataError.
+type AWSMetadataErrorKind int
+
+const (
+	// AWSUnavailable means the IMDS did not respond, or another cloud's metadata server answered instead:
+	// the process is not running on EC2.
+	AWSUnavailable AWSMetadataErrorKind = iota
+	// AWSTokenUnavailable means an IMDSv2 token is required by the client but could not be obtained.
+	AWSTokenUnavailable
+	// AWSTokenRequired means the instance enforces IMDSv2 and rejected a request without a token.
+	AWSTokenRequired
+	// AWSEndpointDisabled means the IMDS is turned off for the instance.
+	AWSEndpointDisabled
+	// AWSTagsDisabled means access to instance tags in the IMDS is not enabled for the instance.
+	AWSTagsDisabled
+	// AWSNotFound means the path does not exist for the instance.
+	AWSNotFound
+	// AWSRequestFailed covers any other failure, including the cancellation of the caller's context.
+	AWSRequestFailed
+)
+
+// hopLimitHint explains the usual cause of IMDSv2 token failures in containers.
+const hopLimitHint = "in a container, the IMDSv2 token response is dropped when the instance metadata hop limit is 1; " +
+	"raise it with: aws ec2 modify-instance-metadata-options --instance-id <id> --http-put-response-hop-limit 2"
+
+// AWSMetadataError reports a failed AWS instance metadata lookup. Use Kind, errors.Is(err, ErrNotAWS)
+// or Misconfigured to tell a process that is not on AWS from an instance whose IMDS is misconfigured.
+type AWSMetadataError struct {
+	Kind AWSMetadataErrorKind
+	Path string
+	// StatusCode is the HTTP status of the response, or 0 if there was none.
+	StatusCode int
+	Err        error
+}
+
+func (e *AWSMetadataError) Error() string {
+	prefix := "failed to get AWS metadata " + e.Path
+	switch e.Kind {
+	case AWSUnavailable:
+		return fmt.Sprintf("%s: instance metadata service is not reachable, not running on EC2: %v", prefix, e.Err)
+	case AWSTokenUnavailable:
+		return fmt.Sprintf("%s: could not obtain an IMDSv2 token: %v; %s", prefix, e.Err, hopLimitHint)
+	case AWSTokenRequired:
+		return fmt.Sprintf("%s: the instance requires IMDSv2 and no token could be obtained (%v); %s", prefix, e.Err, hopLimitHint)
+	case AWSEndpointDisabled:
+		return prefix + ": the instance metadata service is disabled for this instance; " +
+			"enable it with: aws ec2 modify-instance-metadata-options --instance-id <id> --http-endpoint enabled"
+	case AWSTagsDisabled:
+		return prefix + ": access to instance tags in instance metadata is not enabled; " +
+			"enable it with: aws ec2 modify-instance-metadata-options --instance-id <id> --instance-metadata-tags enabled"
+	case AWSNotFound:
+		if e.Err != nil {
+			return fmt.Sprintf("%s: not found: %v", prefix, e.Err)
+		}
+		return prefix + ": not found"
+	default:
+		return fmt.Sprintf("%s: %v", prefix, e.Err)
+	}
+}
+
+func (e *AWSMetadataError) Unwrap() error {
+	return e.Err
+}
+
+// Is reports whether target is ErrNotAWS and the error means the IMDS is not reachable.
+func (e *AWSMetadataError) Is(target error) bool {
+	return target == ErrNotAWS && e.Kind == AWSUnavailable
+}
+
+// Misconfigured reports whether the process runs on EC2 but the IMDS settings prevent the lookup.
+func (e *AWSMetadataError) Misconfigured() bool {
+	switch e.Kind {
+	case AWSTokenUnavailable, AWSTokenRequired, AWSEndpointDisabled, AWSTagsDisabled:
+		return true
+	default:
+		return false
+	}
+}
+
+// isTimeout reports whether err is a request timeout.
+func isTimeout(err error) bool {
+	var netErr net.Error
+	return errors.Is(err, context.DeadlineExceeded) || (errors.As(err, &netErr) && netErr.Timeout())
+}
+
+// statusCode returns the HTTP status of a failed metadata response, or 0 if err is not a status error.
+func statusCode(err error) int {
+	var statusErr *statusError
+	if errors.As(err, &statusErr) {
+		return statusErr.StatusCode
+	}
+	return 0
+}
+
+// isGCPMetadataServer reports whether err is a response from the GCP metadata server, which also listens on
+// the link-local address and rejects requests without the Metadata-Flavor header.
+func isGCPMetadataServer(err error) bool {
+	var statusErr *statusError
+	return errors.As(err, &statusErr) && statusErr.Header.Get("Metadata-Flavor") == "Google"
+}
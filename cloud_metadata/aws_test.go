@@ -0,0 +1,306 @@
+package cloud_metadata
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newHopLimitedAWSServer behaves like an instance that enforces IMDSv2 from a container behind an
+// extra network hop: token responses never arrive, and requests without a token are rejected.
+func newHopLimitedAWSServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == imdsTokenPath {
+			<-r.Context().Done()
+			return
+		}
+		http.Error(w, "missing token", http.StatusUnauthorized)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func awsMetadataErrorKind(t *testing.T, err error) AWSMetadataErrorKind {
+	t.Helper()
+	var metadataErr *AWSMetadataError
+	if !errors.As(err, &metadataErr) {
+		t.Fatalf("expected an AWSMetadataError, got %v", err)
+	}
+	return metadataErr.Kind
+}
+
+func TestClient_AWSRequireIMDSv2(t *testing.T) {
+	server := newHopLimitedAWSServer(t)
+	client := NewClient(&ClientOptions{
+		AWSBaseURL:       server.URL,
+		AWSRequireIMDSv2: true,
+		HTTPClient:       &http.Client{Timeout: 100 * time.Millisecond},
+	})
+
+	_, err := client.GetAWSEC2InstanceID(context.Background())
+	if kind := awsMetadataErrorKind(t, err); kind != AWSTokenUnavailable {
+		t.Errorf("expected AWSTokenUnavailable, got %v: %v", kind, err)
+	}
+	if !strings.Contains(err.Error(), "--http-put-response-hop-limit 2") {
+		t.Errorf("expected a hop limit hint, got %v", err)
+	}
+	if errors.Is(err, ErrNotAWS) {
+		t.Errorf("a misconfigured instance should not match ErrNotAWS: %v", err)
+	}
+}
+
+func TestClient_AWSTokenRequired(t *testing.T) {
+	server := newHopLimitedAWSServer(t)
+	client := NewClient(&ClientOptions{
+		AWSBaseURL:   server.URL,
+		AzureBaseURL: "http://127.0.0.1:1",
+		GCPBaseURL:   "http://127.0.0.1:1",
+		HTTPClient:   &http.Client{Timeout: 100 * time.Millisecond},
+	})
+
+	// Without AWSRequireIMDSv2 the client falls back to IMDSv1, which the instance rejects
+	_, err := client.GetAWSEC2InstanceID(context.Background())
+	if kind := awsMetadataErrorKind(t, err); kind != AWSTokenRequired {
+		t.Errorf("expected AWSTokenRequired, got %v: %v", kind, err)
+	}
+	if !strings.Contains(err.Error(), "--http-put-response-hop-limit 2") {
+		t.Errorf("expected a hop limit hint, got %v", err)
+	}
+
+	clearRuntimeEnv(t)
+	if env := client.Detect(context.Background()); env.Provider != ProviderEC2 {
+		t.Errorf("expected %s for a misconfigured instance, got %s", ProviderEC2, env.Provider)
+	}
+	resource := client.DetectMonitoredResource(context.Background(), "my-project", nil)
+	if resource.Type != "generic_node" || resource.Labels["node_id"] == "" || resource.Labels["location"] != "global" {
+		t.Errorf("expected a generic_node resource for a misconfigured instance, got %v", resource)
+	}
+}
+
+func TestClient_AWSTokenFailureBackoff(t *testing.T) {
+	var tokenRequests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == imdsTokenPath {
+			tokenRequests.Add(1)
+			<-r.Context().Done()
+			return
+		}
+		http.Error(w, "missing token", http.StatusUnauthorized)
+	}))
+	t.Cleanup(server.Close)
+
+	client := NewClient(&ClientOptions{
+		AWSBaseURL:   server.URL,
+		DisableCache: true,
+		HTTPClient:   &http.Client{Timeout: 100 * time.Millisecond},
+	})
+
+	// Parallel lookups share one token request, and later lookups do not retry it
+	var wg sync.WaitGroup
+	for range 5 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			client.GetAWSRegion(context.Background())
+		}()
+	}
+	wg.Wait()
+	_, err := client.GetAWSEC2InstanceID(context.Background())
+	if kind := awsMetadataErrorKind(t, err); kind != AWSTokenRequired {
+		t.Errorf("expected AWSTokenRequired, got %v: %v", kind, err)
+	}
+	if n := tokenRequests.Load(); n != 1 {
+		t.Errorf("expected a single token request, got %d", n)
+	}
+}
+
+func TestClient_AWSRejectedToken(t *testing.T) {
+	var tokenRequests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == imdsTokenPath {
+			fmt.Fprintf(w, "token-%d", tokenRequests.Add(1))
+			return
+		}
+		if r.Header.Get("X-aws-ec2-metadata-token") != "token-2" {
+			http.Error(w, "invalid token", http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte("i-123"))
+	}))
+	t.Cleanup(server.Close)
+
+	client := NewClient(&ClientOptions{AWSBaseURL: server.URL, DisableCache: true})
+	_, err := client.GetAWSEC2InstanceID(context.Background())
+	if kind := awsMetadataErrorKind(t, err); kind != AWSTokenRequired {
+		t.Errorf("expected AWSTokenRequired, got %v: %v", kind, err)
+	}
+
+	// The rejected token is discarded
+	if id, err := client.GetAWSEC2InstanceID(context.Background()); err != nil || id != "i-123" {
+		t.Errorf("expected i-123 with a new token, got %q, %v", id, err)
+	}
+	if n := tokenRequests.Load(); n != 2 {
+		t.Errorf("expected 2 token requests, got %d", n)
+	}
+}
+
+func TestClient_AWSCanceled(t *testing.T) {
+	server := newHopLimitedAWSServer(t)
+	client := NewClient(&ClientOptions{AWSBaseURL: server.URL})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := client.GetAWSEC2InstanceID(ctx)
+	if kind := awsMetadataErrorKind(t, err); kind != AWSRequestFailed {
+		t.Errorf("expected AWSRequestFailed, got %v: %v", kind, err)
+	}
+	if errors.Is(err, ErrNotAWS) || !errors.Is(err, context.Canceled) {
+		t.Errorf("expected a cancellation that does not match ErrNotAWS, got %v", err)
+	}
+}
+
+func TestClient_AWSOnGCE(t *testing.T) {
+	// The GCP metadata server rejects requests without the Metadata-Flavor header
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Metadata-Flavor", "Google")
+		http.Error(w, "Missing Metadata-Flavor:Google header.", http.StatusForbidden)
+	}))
+	t.Cleanup(server.Close)
+
+	client := NewClient(&ClientOptions{AWSBaseURL: server.URL})
+	_, err := client.GetAWSEC2InstanceID(context.Background())
+	if !errors.Is(err, ErrNotAWS) {
+		t.Errorf("expected ErrNotAWS, got %v", err)
+	}
+}
+
+func TestClient_AWSIMDSv1Fallback(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == imdsTokenPath {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte("i-123"))
+	}))
+	t.Cleanup(server.Close)
+
+	client := NewClient(&ClientOptions{AWSBaseURL: server.URL})
+	if id, err := client.GetAWSEC2InstanceID(context.Background()); err != nil || id != "i-123" {
+		t.Errorf("expected i-123, got %q, %v", id, err)
+	}
+
+	client = NewClient(&ClientOptions{AWSBaseURL: server.URL, AWSRequireIMDSv2: true})
+	_, err := client.GetAWSEC2InstanceID(context.Background())
+	if kind := awsMetadataErrorKind(t, err); kind != AWSTokenUnavailable {
+		t.Errorf("expected AWSTokenUnavailable, got %v: %v", kind, err)
+	}
+}
+
+func TestClient_AWSEndpointDisabled(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "forbidden", http.StatusForbidden)
+	}))
+	t.Cleanup(server.Close)
+
+	client := NewClient(&ClientOptions{AWSBaseURL: server.URL})
+	_, err := client.GetAWSRegion(context.Background())
+	if kind := awsMetadataErrorKind(t, err); kind != AWSEndpointDisabled {
+		t.Errorf("expected AWSEndpointDisabled, got %v: %v", kind, err)
+	}
+}
+
+func TestClient_AWSAutoScalingGroupName(t *testing.T) {
+	tests := []struct {
+		name   string
+		values map[string]string
+		want   string
+		kind   AWSMetadataErrorKind
+	}{
+		{
+			name: "in group",
+			values: map[string]string{
+				"/latest/meta-data/tags/instance": "Name\naws:autoscaling:groupName",
+				awsAutoScalingGroupTagPath:        "web",
+			},
+			want: "web",
+		},
+		{
+			name:   "tags disabled",
+			values: map[string]string{},
+			kind:   AWSTagsDisabled,
+		},
+		{
+			name:   "not in group",
+			values: map[string]string{"/latest/meta-data/tags/instance": "Name"},
+			kind:   AWSNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newFakeAWSServer(t, tt.values)
+			client := NewClient(&ClientOptions{AWSBaseURL: server.URL})
+
+			got, err := client.GetAWSAutoScalingGroupName(context.Background())
+			if tt.want != "" {
+				if err != nil || got != tt.want {
+					t.Errorf("expected %q, got %q, %v", tt.want, got, err)
+				}
+				return
+			}
+			if kind := awsMetadataErrorKind(t, err); kind != tt.kind {
+				t.Errorf("expected kind %v, got %v: %v", tt.kind, kind, err)
+			}
+		})
+	}
+}
+
+func TestClient_AWSNotAWS(t *testing.T) {
+	client := NewClient(&ClientOptions{AWSBaseURL: "http://127.0.0.1:1"})
+
+	_, err := client.GetAWSEC2InstanceID(context.Background())
+	if !errors.Is(err, ErrNotAWS) {
+		t.Errorf("expected ErrNotAWS, got %v", err)
+	}
+	var metadataErr *AWSMetadataError
+	if errors.As(err, &metadataErr) && metadataErr.Misconfigured() {
+		t.Errorf("an unreachable endpoint should not be misconfigured: %v", err)
+	}
+}
+
+func TestClient_AWSTokenTTL(t *testing.T) {
+	var mu sync.Mutex
+	var ttls []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == imdsTokenPath {
+			mu.Lock()
+			ttls = append(ttls, r.Header.Get("X-aws-ec2-metadata-token-ttl-seconds"))
+			mu.Unlock()
+			w.Write([]byte("token"))
+			return
+		}
+		w.Write([]byte("value"))
+	}))
+	t.Cleanup(server.Close)
+
+	client := NewClient(&ClientOptions{AWSBaseURL: server.URL, AWSTokenTTL: 90 * time.Second})
+	for _, path := range []string{"/latest/meta-data/instance-id", "/latest/meta-data/placement/region"} {
+		if _, err := client.GetAWSMetadata(context.Background(), path); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(ttls) != 1 || ttls[0] != "90" {
+		t.Errorf("expected a single token request with a TTL of 90, got %v", ttls)
+	}
+}
@@ -0,0 +1,76 @@
+package cloud_metadata
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+const (
+	azureIMDSBaseURL = "http://169.254.169.254"
+	// azureAPIVersion is the Azure Instance Metadata Service API version requested.
+	azureAPIVersion = "2021-02-01"
+)
+
+// AzureInstanceInfo describes an Azure VM.
+type AzureInstanceInfo struct {
+	VMID           string `json:"vmId"`
+	Name           string `json:"name"`
+	Location       string `json:"location"`
+	Zone           string `json:"zone"`
+	ResourceGroup  string `json:"resourceGroupName"`
+	SubscriptionID string `json:"subscriptionId"`
+	// ScaleSetName is the virtual machine scale set, if the VM belongs to one.
+	ScaleSetName string `json:"vmScaleSetName"`
+}
+
+// GetAzureInstanceInfo returns the VM ID, name, location, resource group and subscription from the Azure IMDS
+func (c *Client) GetAzureInstanceInfo(ctx context.Context) (*AzureInstanceInfo, error) {
+	data, err := c.GetAzureMetadata(ctx, "/metadata/instance/compute", "json")
+	if err != nil {
+		return nil, err
+	}
+
+	info := &AzureInstanceInfo{}
+	if err := json.Unmarshal([]byte(data), info); err != nil {
+		return nil, fmt.Errorf("failed to parse Azure compute metadata: %v", err)
+	}
+	if info.VMID == "" {
+		return nil, fmt.Errorf("Azure compute metadata has no vmId")
+	}
+	return info, nil
+}
+
+// GetAzureVMID returns the Azure VM ID from the Azure IMDS
+func (c *Client) GetAzureVMID(ctx context.Context) (string, error) {
+	return c.GetAzureMetadata(ctx, "/metadata/instance/compute/vmId", "text")
+}
+
+// GetAzureMetadata fetches a metadata value from the given path using the Azure IMDS,
+// in the given format (json or text)
+func (c *Client) GetAzureMetadata(ctx context.Context, path, format string) (string, error) {
+	query := url.Values{"api-version": {azureAPIVersion}, "format": {format}}
+	return c.cachedGet(ctx, c.azureBaseURL+path+"?"+query.Encode(), func(context.Context) http.Header {
+		header := http.Header{}
+		header.Set("Metadata", "true")
+		return header
+	})
+}
+
+// GetAzureInstanceInfo returns the VM ID, name, location, resource group and subscription from the Azure IMDS
+func GetAzureInstanceInfo() (*AzureInstanceInfo, error) {
+	return DefaultClient().GetAzureInstanceInfo(context.Background())
+}
+
+// GetAzureVMID returns the Azure VM ID from the Azure IMDS
+func GetAzureVMID() (string, error) {
+	return DefaultClient().GetAzureVMID(context.Background())
+}
+
+// GetAzureMetadata fetches a metadata value from the given path using the Azure IMDS,
+// in the given format (json or text)
+func GetAzureMetadata(path, format string) (string, error) {
+	return DefaultClient().GetAzureMetadata(context.Background(), path, format)
+}
@@ -0,0 +1,48 @@
+package cloud_metadata
+
+import (
+	"context"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestAzure(t *testing.T) {
+	clearRuntimeEnv(t)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Metadata") != "true" || r.URL.Query().Get("api-version") == "" {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		switch r.URL.Path {
+		case "/metadata/instance/compute":
+			w.Write([]byte(`{"vmId": "02aab8a4-74ef-476e-8182-f6d2ba4166a6", "name": "worker-1", "location": "westeurope",
+				"zone": "1", "resourceGroupName": "workers", "subscriptionId": "8d10da13-8125-4ba9-a717-bf7490507b3d"}`))
+		case "/metadata/instance/compute/vmId":
+			w.Write([]byte("02aab8a4-74ef-476e-8182-f6d2ba4166a6"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+	client := NewClient(&ClientOptions{GCPBaseURL: "http://127.0.0.1:1", AWSBaseURL: "http://127.0.0.1:1", AzureBaseURL: server.URL})
+	ctx := context.Background()
+
+	if id, err := client.GetAzureVMID(ctx); err != nil || id != "02aab8a4-74ef-476e-8182-f6d2ba4166a6" {
+		t.Errorf("GetAzureVMID() = %q, %v", id, err)
+	}
+
+	env := client.Detect(ctx)
+	expected := Environment{Provider: ProviderAzure, AccountID: "8d10da13-8125-4ba9-a717-bf7490507b3d", Region: "westeurope",
+		Zone: "1", InstanceID: "02aab8a4-74ef-476e-8182-f6d2ba4166a6", InstanceName: "worker-1"}
+	if *env != expected {
+		t.Errorf("Detect() = %+v, expected %+v", *env, expected)
+	}
+
+	resource := client.DetectMonitoredResource(ctx, "my-project", nil)
+	expectedLabels := map[string]string{"project_id": "my-project", "location": "westeurope", "namespace": "workers", "node_id": "worker-1"}
+	if resource.Type != "generic_node" || !maps.Equal(resource.Labels, expectedLabels) {
+		t.Errorf("unexpected resource %s %v", resource.Type, resource.Labels)
+	}
+}
@@ -0,0 +1,126 @@
+package cloud_metadata
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// transientErrorTTL is how long a failed lookup that may succeed on retry, such as a timeout or a
+// server error, is cached. It is a variable so that tests can shorten it.
+var transientErrorTTL = 10 * time.Second
+
+// cacheEntry holds the result of one metadata lookup. done is closed once value, err and expiry are set.
+type cacheEntry struct {
+	done   chan struct{}
+	value  string
+	err    error
+	expiry time.Time // Zero if the result does not expire
+}
+
+// expired reports whether a completed entry should be fetched again.
+func (e *cacheEntry) expired(now time.Time) bool {
+	select {
+	case <-e.done:
+		return !e.expiry.IsZero() && !now.Before(e.expiry)
+	default:
+		return false
+	}
+}
+
+// metadataCache memoizes metadata lookups, keyed by URL, and collapses concurrent lookups of the same
+// key into a single request.
+type metadataCache struct {
+	mu      sync.Mutex
+	entries map[string]*cacheEntry // Guarded by mu
+}
+
+func newMetadataCache() *metadataCache {
+	return &metadataCache{entries: make(map[string]*cacheEntry)}
+}
+
+// cachedGet returns the memoized result of a GET of url. See cached.
+func (c *Client) cachedGet(ctx context.Context, url string, header func(context.Context) http.Header) (string, error) {
+	return c.cached(ctx, url, func(ctx context.Context) (string, error) {
+		return c.get(ctx, http.MethodGet, url, header(ctx))
+	})
+}
+
+// cached returns the memoized result of fetch for key, calling it if this is the first lookup.
+// Successful results and definitive failures (see isDefinitive) are cached for the lifetime of the
+// Client, so that missing values and metadata servers are not looked up again. Other failures are
+// cached for transientErrorTTL, and failures caused by the cancellation of the fetching context are
+// not cached: callers that were waiting on such a fetch retry it with their own context.
+func (c *Client) cached(ctx context.Context, key string, fetch func(context.Context) (string, error)) (string, error) {
+	if c.cache == nil {
+		return fetch(ctx)
+	}
+
+	for {
+		c.cache.mu.Lock()
+		entry, ok := c.cache.entries[key]
+		if !ok || entry.expired(time.Now()) {
+			entry = &cacheEntry{done: make(chan struct{})}
+			c.cache.entries[key] = entry
+			c.cache.mu.Unlock()
+
+			c.fill(ctx, key, entry, fetch)
+			return entry.value, entry.err
+		}
+		c.cache.mu.Unlock()
+
+		select {
+		case <-entry.done:
+		case <-ctx.Done():
+			return "", fmt.Errorf("failed to get %s: %w", key, ctx.Err())
+		}
+
+		c.cache.mu.Lock()
+		retry := c.cache.entries[key] != entry
+		c.cache.mu.Unlock()
+		if !retry {
+			return entry.value, entry.err
+		}
+	}
+}
+
+// fill calls fetch and stores its result in entry. The entry is removed from the cache if the result
+// must not be cached, including when fetch panics, and waiters are released in every case.
+func (c *Client) fill(ctx context.Context, key string, entry *cacheEntry, fetch func(context.Context) (string, error)) {
+	completed := false
+	defer func() {
+		if !completed || (entry.err != nil && ctx.Err() != nil) {
+			c.cache.mu.Lock()
+			delete(c.cache.entries, key)
+			c.cache.mu.Unlock()
+		}
+		close(entry.done)
+	}()
+
+	entry.value, entry.err = fetch(ctx)
+	if entry.err != nil && !isDefinitive(entry.err) {
+		entry.expiry = time.Now().Add(transientErrorTTL)
+	}
+	completed = true
+}
+
+// isDefinitive reports whether a failed lookup is a lasting answer: the value does not exist,
+// or the metadata server's host name does not resolve. Refused connections are not definitive, since
+// a metadata server may not accept connections yet, for example at pod start on GKE.
+func isDefinitive(err error) bool {
+	var metadataErr *AWSMetadataError
+	var statusErr *statusError
+	var dnsErr *net.DNSError
+	switch {
+	case errors.As(err, &metadataErr) && metadataErr.StatusCode != 0:
+		return metadataErr.StatusCode == http.StatusNotFound
+	case errors.As(err, &statusErr):
+		return statusErr.StatusCode == http.StatusNotFound
+	default:
+		return errors.As(err, &dnsErr) && dnsErr.IsNotFound
+	}
+}
@@ -0,0 +1,166 @@
+package cloud_metadata
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// gcpMetadataHostEnv overrides the host[:port] of the GCP metadata server, as in the Google Cloud client libraries.
+	gcpMetadataHostEnv = "GCE_METADATA_HOST"
+	// awsMetadataEndpointEnv overrides the base URL of the AWS IMDS, as in the AWS SDKs.
+	awsMetadataEndpointEnv = "AWS_EC2_METADATA_SERVICE_ENDPOINT"
+	// defaultTimeout bounds each metadata request made by the default HTTP client.
+	defaultTimeout = 2 * time.Second
+)
+
+// ClientOptions contains optional configuration for Client.
+type ClientOptions struct {
+	// GCPBaseURL is the base URL of the GCP metadata server.
+	// Defaults to http:// plus $GCE_METADATA_HOST if set, or http://metadata.google.internal.
+	GCPBaseURL string
+	// AWSBaseURL is the base URL of the AWS instance metadata service.
+	// Defaults to $AWS_EC2_METADATA_SERVICE_ENDPOINT if set, or http://169.254.169.254.
+	AWSBaseURL string
+	// AWSRequireIMDSv2 makes AWS lookups fail with AWSTokenUnavailable when no IMDSv2 token can be
+	// obtained, instead of falling back to IMDSv1.
+	AWSRequireIMDSv2 bool
+	// AWSTokenTTL is the lifetime requested for IMDSv2 tokens, at most 6 hours. Defaults to 6 hours.
+	AWSTokenTTL time.Duration
+	// AzureBaseURL is the base URL of the Azure Instance Metadata Service. Defaults to http://169.254.169.254.
+	AzureBaseURL string
+	// ECSMetadataURI is the ECS Task Metadata Endpoint v4 of the container.
+	// Defaults to $ECS_CONTAINER_METADATA_URI_V4, which the ECS agent sets; empty outside ECS.
+	ECSMetadataURI string
+	// HTTPClient is used for all requests. Defaults to a client with a 2s timeout.
+	HTTPClient *http.Client
+	// DisableCache makes every lookup issue a new request. By default values and missing values are
+	// memoized, since instance metadata does not change over the lifetime of a process, and other
+	// failures are remembered for a few seconds.
+	DisableCache bool
+}
+
+// Client fetches instance metadata from the GCP metadata server, the AWS IMDS, the ECS Task Metadata Endpoint
+// and the Azure IMDS.
+// All methods honor the cancellation and deadline of their context.
+//
+// Unless DisableCache is set, a Client memoizes lookups and the detected Environment, collapses concurrent lookups of the
+// same value into a single request, and shares one IMDSv2 token between requests until it expires.
+// A Client is safe for concurrent use.
+type Client struct {
+	gcpBaseURL       string
+	awsBaseURL       string
+	awsRequireIMDSv2 bool
+	awsTokenTTL      time.Duration
+	azureBaseURL     string
+	ecsMetadataURI   string
+	httpClient       *http.Client
+	cache            *metadataCache // nil if caching is disabled
+	tokenMu          sync.Mutex
+	token            string      // Guarded by tokenMu
+	tokenExpiry      time.Time   // Guarded by tokenMu
+	tokenErr         error       // Guarded by tokenMu; the last failed token request
+	tokenRetry       time.Time   // Guarded by tokenMu; when a token may be requested again after tokenErr
+	tokenFetch       *tokenFetch // Guarded by tokenMu; the token request in progress, if any
+	envMu            sync.Mutex
+	env              *Environment // Guarded by envMu; the memoized result of Detect
+}
+
+// NewClient creates a new Client. opts may be nil.
+func NewClient(opts *ClientOptions) *Client {
+	// Set defaults if nil
+	if opts == nil {
+		opts = &ClientOptions{}
+	}
+	c := &Client{
+		gcpBaseURL:       opts.GCPBaseURL,
+		awsBaseURL:       opts.AWSBaseURL,
+		awsRequireIMDSv2: opts.AWSRequireIMDSv2,
+		awsTokenTTL:      opts.AWSTokenTTL,
+		azureBaseURL:     cmp.Or(opts.AzureBaseURL, azureIMDSBaseURL),
+		ecsMetadataURI:   cmp.Or(opts.ECSMetadataURI, os.Getenv(ecsMetadataURIEnv)),
+		httpClient:       opts.HTTPClient,
+	}
+	if c.gcpBaseURL == "" {
+		c.gcpBaseURL = gcpMetadataBaseURL
+		if host := os.Getenv(gcpMetadataHostEnv); host != "" {
+			c.gcpBaseURL = "http://" + host
+		}
+	}
+	if c.awsBaseURL == "" {
+		c.awsBaseURL = imdsBaseURL
+		if endpoint := os.Getenv(awsMetadataEndpointEnv); endpoint != "" {
+			c.awsBaseURL = endpoint
+		}
+	}
+	c.gcpBaseURL = strings.TrimSuffix(c.gcpBaseURL, "/")
+	c.awsBaseURL = strings.TrimSuffix(c.awsBaseURL, "/")
+	c.azureBaseURL = strings.TrimSuffix(c.azureBaseURL, "/")
+	c.ecsMetadataURI = strings.TrimSuffix(c.ecsMetadataURI, "/")
+	if c.awsTokenTTL <= 0 {
+		c.awsTokenTTL = defaultIMDSTokenTTL
+	}
+	c.awsTokenTTL = min(max(c.awsTokenTTL.Truncate(time.Second), time.Second), maxIMDSTokenTTL)
+	if c.httpClient == nil {
+		c.httpClient = &http.Client{Timeout: defaultTimeout}
+	}
+	if !opts.DisableCache {
+		c.cache = newMetadataCache()
+	}
+	return c
+}
+
+// defaultClient is used by the package-level functions. It is created on first use,
+// so that environment overrides set during program initialization are honored.
+var defaultClient = sync.OnceValue(func() *Client {
+	return NewClient(nil)
+})
+
+// DefaultClient returns the Client used by the package-level functions.
+func DefaultClient() *Client {
+	return defaultClient()
+}
+
+// statusError reports a metadata response with a status other than 200 OK.
+type statusError struct {
+	URL        string
+	StatusCode int
+	Header     http.Header
+}
+
+func (e *statusError) Error() string {
+	return fmt.Sprintf("failed to get %s with HTTP status %d", e.URL, e.StatusCode)
+}
+
+// get performs a metadata request and returns the response body.
+func (c *Client) get(ctx context.Context, method, url string, header http.Header) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, method, url, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to create request for %s: %v", url, err)
+	}
+	req.Header = header
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to get %s: %w", url, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", &statusError{URL: url, StatusCode: resp.StatusCode, Header: resp.Header}
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", fmt.Errorf("failed to read response for %s: %v", url, err)
+	}
+
+	return string(body), nil
+}
@@ -0,0 +1,277 @@
+package cloud_metadata
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// clearRuntimeEnv unsets the environment variables that identify serverless and container runtimes.
+func clearRuntimeEnv(t *testing.T) {
+	t.Helper()
+	for _, name := range []string{"K_SERVICE", "K_REVISION", "K_CONFIGURATION", "CLOUD_RUN_JOB", "CLOUD_RUN_EXECUTION",
+		"CLOUD_RUN_TASK_INDEX", "FUNCTION_TARGET", "FUNCTION_NAME", "GAE_SERVICE", "GAE_VERSION", "GAE_INSTANCE",
+		"KUBERNETES_SERVICE_HOST", "ECS_CONTAINER_METADATA_URI_V4"} {
+		t.Setenv(name, "")
+	}
+}
+
+// newFakeGCPServer serves the given metadata paths, requiring the Metadata-Flavor header.
+func newFakeGCPServer(t *testing.T, values map[string]string) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Metadata-Flavor") != "Google" {
+			http.Error(w, "missing Metadata-Flavor header", http.StatusForbidden)
+			return
+		}
+		value, ok := values[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(value))
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+// newFakeAWSServer serves the given metadata paths, requiring an IMDSv2 token.
+func newFakeAWSServer(t *testing.T, values map[string]string) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == imdsTokenPath {
+			if r.Method != http.MethodPut || r.Header.Get("X-aws-ec2-metadata-token-ttl-seconds") == "" {
+				http.Error(w, "bad token request", http.StatusBadRequest)
+				return
+			}
+			w.Write([]byte("token"))
+			return
+		}
+		if r.Header.Get("X-aws-ec2-metadata-token") != "token" {
+			http.Error(w, "missing token", http.StatusUnauthorized)
+			return
+		}
+		value, ok := values[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(value))
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestClient_GCP(t *testing.T) {
+	clearRuntimeEnv(t)
+	server := newFakeGCPServer(t, map[string]string{
+		"/computeMetadata/v1/instance/id":        "1234",
+		"/computeMetadata/v1/project/project-id": "my-project",
+		"/computeMetadata/v1/instance/zone":      "projects/123/zones/us-central1-a",
+	})
+	client := NewClient(&ClientOptions{GCPBaseURL: server.URL, AWSBaseURL: "http://127.0.0.1:1", AzureBaseURL: "http://127.0.0.1:1"})
+	ctx := context.Background()
+
+	if id, err := client.GetGCPInstanceID(ctx); err != nil || id != "1234" {
+		t.Errorf("GetGCPInstanceID() = %q, %v", id, err)
+	}
+	if _, err := client.GetGCPRegion(ctx); err == nil || !strings.Contains(err.Error(), "404") {
+		t.Errorf("expected HTTP 404 error, got %v", err)
+	}
+
+	resource := client.DetectMonitoredResource(ctx, "", nil)
+	if resource.Type != "gce_instance" || resource.Labels["project_id"] != "my-project" ||
+		resource.Labels["instance_id"] != "1234" || resource.Labels["zone"] != "us-central1-a" {
+		t.Errorf("unexpected resource %v", resource)
+	}
+}
+
+func TestClient_GCPMetadataHostEnv(t *testing.T) {
+	server := newFakeGCPServer(t, map[string]string{"/computeMetadata/v1/instance/name": "vm-1"})
+	t.Setenv(gcpMetadataHostEnv, strings.TrimPrefix(server.URL, "http://"))
+
+	if name, err := NewClient(nil).GetGCPInstanceName(context.Background()); err != nil || name != "vm-1" {
+		t.Errorf("GetGCPInstanceName() = %q, %v", name, err)
+	}
+}
+
+func TestClient_AWS(t *testing.T) {
+	clearRuntimeEnv(t)
+	server := newFakeAWSServer(t, map[string]string{
+		"/latest/meta-data/instance-id":              "i-123",
+		"/latest/meta-data/placement/region":         "us-east-1",
+		"/latest/dynamic/instance-identity/document": `{"accountId": "111122223333"}`,
+	})
+	client := NewClient(&ClientOptions{GCPBaseURL: "http://127.0.0.1:1", AWSBaseURL: server.URL, AzureBaseURL: "http://127.0.0.1:1"})
+	ctx := context.Background()
+
+	if account, err := client.GetAWSAccountID(ctx); err != nil || account != "111122223333" {
+		t.Errorf("GetAWSAccountID() = %q, %v", account, err)
+	}
+
+	resource := client.DetectMonitoredResource(ctx, "my-project", nil)
+	if resource.Type != "aws_ec2_instance" || resource.Labels["instance_id"] != "i-123" ||
+		resource.Labels["region"] != "aws:us-east-1" || resource.Labels["aws_account"] != "111122223333" {
+		t.Errorf("unexpected resource %v", resource)
+	}
+}
+
+func TestClient_ContextCancellation(t *testing.T) {
+	blocked := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-blocked
+	}))
+	defer server.Close()
+	defer close(blocked)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	client := NewClient(&ClientOptions{GCPBaseURL: server.URL, HTTPClient: &http.Client{}})
+	if _, err := client.GetGCPInstanceID(ctx); err == nil || !strings.Contains(err.Error(), "context canceled") {
+		t.Errorf("expected context canceled error, got %v", err)
+	}
+}
+
+func TestClient_CachingAndSingleFlight(t *testing.T) {
+	var tokenRequests, instanceIDRequests atomic.Int32
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case imdsTokenPath:
+			tokenRequests.Add(1)
+			w.Write([]byte("token"))
+		case "/latest/meta-data/instance-id":
+			instanceIDRequests.Add(1)
+			<-release
+			w.Write([]byte("i-123"))
+		case "/latest/meta-data/placement/region":
+			w.Write([]byte("us-east-1"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+	client := NewClient(&ClientOptions{AWSBaseURL: server.URL})
+	ctx := context.Background()
+
+	// Concurrent lookups of the same value share one request
+	var wg sync.WaitGroup
+	for range 10 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if id, err := client.GetAWSEC2InstanceID(ctx); err != nil || id != "i-123" {
+				t.Errorf("GetAWSEC2InstanceID() = %q, %v", id, err)
+			}
+		}()
+	}
+	for instanceIDRequests.Load() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	close(release)
+	wg.Wait()
+
+	// Later lookups are served from the cache, and other values reuse the token
+	client.GetAWSEC2InstanceID(ctx)
+	client.GetAWSRegion(ctx)
+	client.GetAWSRegion(ctx)
+	if n := instanceIDRequests.Load(); n != 1 {
+		t.Errorf("expected 1 instance ID request, got %d", n)
+	}
+	if n := tokenRequests.Load(); n != 1 {
+		t.Errorf("expected 1 token request, got %d", n)
+	}
+}
+
+func TestClient_CachesOnlyDefinitiveErrors(t *testing.T) {
+	ttl := transientErrorTTL
+	transientErrorTTL = 0
+	t.Cleanup(func() { transientErrorTTL = ttl })
+
+	var requests sync.Map
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n, _ := requests.LoadOrStore(r.URL.Path, new(atomic.Int64))
+		switch {
+		case r.URL.Path == "/computeMetadata/v1/project/project-id" && n.(*atomic.Int64).Add(1) == 1:
+			http.Error(w, "slow start", http.StatusServiceUnavailable)
+		case r.URL.Path == "/computeMetadata/v1/project/project-id":
+			w.Write([]byte("my-project"))
+		default:
+			n.(*atomic.Int64).Add(1)
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+	client := NewClient(&ClientOptions{GCPBaseURL: server.URL})
+	ctx := context.Background()
+
+	// A server error is retried once it expires
+	if _, err := client.GetGCPProjectID(ctx); err == nil {
+		t.Fatal("expected the first lookup to fail")
+	}
+	if id, err := client.GetGCPProjectID(ctx); err != nil || id != "my-project" {
+		t.Errorf("GetGCPProjectID() = %q, %v", id, err)
+	}
+
+	// A missing value is cached
+	client.GetGCPMetadata(ctx, "/computeMetadata/v1/instance/attributes/missing")
+	client.GetGCPMetadata(ctx, "/computeMetadata/v1/instance/attributes/missing")
+	if n, _ := requests.Load("/computeMetadata/v1/instance/attributes/missing"); n.(*atomic.Int64).Load() != 1 {
+		t.Errorf("expected 1 request for a missing value, got %d", n.(*atomic.Int64).Load())
+	}
+}
+
+func TestIsDefinitive(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"not found", &statusError{StatusCode: http.StatusNotFound}, true},
+		{"server error", &statusError{StatusCode: http.StatusServiceUnavailable}, false},
+		{"AWS not found", &AWSMetadataError{Kind: AWSNotFound, StatusCode: http.StatusNotFound}, true},
+		{"host not found", &net.OpError{Op: "dial", Err: &net.DNSError{IsNotFound: true}}, true},
+		{"temporary DNS failure", &net.OpError{Op: "dial", Err: &net.DNSError{IsTemporary: true}}, false},
+		{"connection refused", fmt.Errorf("request failed: %w", &net.OpError{Op: "dial", Err: syscall.ECONNREFUSED}), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isDefinitive(tt.err); got != tt.want {
+				t.Errorf("isDefinitive(%v) = %v, expected %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestClient_CachedFetchPanic(t *testing.T) {
+	client := NewClient(nil)
+	ctx := context.Background()
+
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Error("expected the panic to propagate")
+			}
+		}()
+		client.cached(ctx, "key", func(context.Context) (string, error) {
+			panic("fetch failed")
+		})
+	}()
+
+	// The panicked lookup is not cached and does not block later lookups
+	value, err := client.cached(ctx, "key", func(context.Context) (string, error) {
+		return "value", nil
+	})
+	if err != nil || value != "value" {
+		t.Errorf("cached() = %q, %v", value, err)
+	}
+}
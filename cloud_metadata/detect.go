@@ -0,0 +1,243 @@
+package cloud_metadata
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// detectProbeTimeout bounds the requests that determine whether a metadata server is present.
+// Off-cloud, the AWS link-local address does not respond, so the probe would otherwise wait for
+// the full HTTP client timeout.
+const detectProbeTimeout = time.Second
+
+// Provider identifies the runtime environment.
+type Provider string
+
+const (
+	ProviderNone           Provider = "none"
+	ProviderGCE            Provider = "gce"
+	ProviderGKE            Provider = "gke"
+	ProviderCloudRun       Provider = "cloud_run"
+	ProviderCloudRunJob    Provider = "cloud_run_job"
+	ProviderCloudFunctions Provider = "cloud_functions"
+	ProviderAppEngine      Provider = "app_engine"
+	ProviderEC2            Provider = "aws_ec2"
+	ProviderECS            Provider = "aws_ecs"
+	ProviderAzure          Provider = "azure_vm"
+)
+
+// serverlessProviders maps serverless runtimes to providers.
+var serverlessProviders = map[ServerlessRuntime]Provider{
+	ServerlessCloudRunService: ProviderCloudRun,
+	ServerlessCloudRunJob:     ProviderCloudRunJob,
+	ServerlessCloudFunction:   ProviderCloudFunctions,
+	ServerlessAppEngine:       ProviderAppEngine,
+}
+
+// IsGCP reports whether the provider is a Google Cloud runtime.
+func (p Provider) IsGCP() bool {
+	switch p {
+	case ProviderGCE, ProviderGKE, ProviderCloudRun, ProviderCloudRunJob, ProviderCloudFunctions, ProviderAppEngine:
+		return true
+	default:
+		return false
+	}
+}
+
+// IsServerless reports whether the provider is a Google Cloud serverless runtime.
+func (p Provider) IsServerless() bool {
+	return p == ProviderCloudRun || p == ProviderCloudRunJob || p == ProviderCloudFunctions || p == ProviderAppEngine
+}
+
+// IsAWS reports whether the provider is an AWS runtime.
+func (p Provider) IsAWS() bool {
+	return p == ProviderEC2 || p == ProviderECS
+}
+
+// Environment describes the runtime environment. Fields that do not apply to the provider,
+// or that could not be read, are empty.
+type Environment struct {
+	Provider Provider
+	// ProjectID is the GCP project ID on Google Cloud.
+	ProjectID string
+	// AccountID is the AWS account ID on AWS, or the subscription ID on Azure.
+	AccountID string
+	Region    string
+	Zone      string
+	// InstanceID is the VM instance ID, the serverless instance ID, or the ECS task ID.
+	InstanceID string
+	// InstanceName is the GCE instance name, the EC2 Name tag, the ECS container name, or the Azure VM name.
+	InstanceName string
+	// GroupName is the managed instance group on GCE and GKE, the Auto Scaling group on EC2,
+	// the service or job on Cloud Run, the function on Cloud Functions, the service on App Engine,
+	// the task definition family on ECS, and the virtual machine scale set on Azure.
+	GroupName string
+}
+
+// Detect determines the runtime environment by querying the GCP, AWS and Azure metadata servers in parallel.
+// Environment variables distinguish the serverless runtimes, GKE and ECS from the VMs they run on.
+// Detect returns an Environment with ProviderNone when no metadata server responds, and one with only
+// ProviderEC2 set when the EC2 metadata service responds but is misconfigured.
+//
+// Unless DisableCache is set, the Environment is memoized once a provider is found, or once every
+// metadata server is known to be absent. A metadata server that is slow to respond, for example at boot,
+// is probed again on the next call. Each call returns a copy.
+func (c *Client) Detect(ctx context.Context) *Environment {
+	if c.cache == nil {
+		env, _ := c.detect(ctx)
+		return env
+	}
+
+	c.envMu.Lock()
+	defer c.envMu.Unlock()
+	if c.env == nil {
+		env, final := c.detect(ctx)
+		if !final || ctx.Err() != nil {
+			return env
+		}
+		c.env = env
+	}
+	env := *c.env
+	return &env
+}
+
+// detect determines the runtime environment, and reports whether the result is final: a provider was
+// found, or no metadata server exists. See Detect.
+func (c *Client) detect(ctx context.Context) (*Environment, bool) {
+	if c.ecsMetadataURI != "" {
+		return c.detectECS(ctx)
+	}
+
+	probeCtx, cancel := context.WithTimeout(ctx, detectProbeTimeout)
+	defer cancel()
+
+	var gcpInstanceID, awsInstanceID string
+	var azureInfo *AzureInstanceInfo
+	var gcpErr, awsErr, azureErr error
+	parallel(
+		func() { gcpInstanceID, gcpErr = c.GetGCPInstanceID(probeCtx) },
+		func() { awsInstanceID, awsErr = c.GetAWSEC2InstanceID(probeCtx) },
+		func() { azureInfo, azureErr = c.GetAzureInstanceInfo(probeCtx) },
+	)
+
+	switch {
+	case gcpErr == nil:
+		return c.detectGCP(ctx, gcpInstanceID), true
+	case awsErr == nil:
+		return c.detectEC2(ctx, awsInstanceID), true
+	case azureErr == nil:
+		return &Environment{
+			Provider:     ProviderAzure,
+			AccountID:    azureInfo.SubscriptionID,
+			Region:       azureInfo.Location,
+			Zone:         azureInfo.Zone,
+			InstanceID:   azureInfo.VMID,
+			InstanceName: azureInfo.Name,
+			GroupName:    azureInfo.ScaleSetName,
+		}, true
+	case isMisconfiguredAWS(awsErr):
+		// On EC2, but the metadata settings prevent further lookups
+		return &Environment{Provider: ProviderEC2}, true
+	default:
+		final := isDefinitive(gcpErr) && (isDefinitive(awsErr) || errors.Is(awsErr, ErrNotAWS)) && isDefinitive(azureErr)
+		return &Environment{Provider: ProviderNone}, final
+	}
+}
+
+// isMisconfiguredAWS reports whether err shows that the process runs on EC2 with an unusable IMDS.
+func isMisconfiguredAWS(err error) bool {
+	var metadataErr *AWSMetadataError
+	return errors.As(err, &metadataErr) && metadataErr.Misconfigured()
+}
+
+// detectGCP fills in the environment on Google Cloud.
+func (c *Client) detectGCP(ctx context.Context, instanceID string) *Environment {
+	env := &Environment{InstanceID: instanceID}
+	var zone, region, clusterName, groupName string
+	parallel(
+		func() { env.ProjectID = valueOrEmpty(c.GetGCPProjectID(ctx)) },
+		func() { env.InstanceName = valueOrEmpty(c.GetGCPInstanceName(ctx)) },
+		func() { zone = valueOrEmpty(c.GetGCPZone(ctx)) },
+		func() { region = valueOrEmpty(c.GetGCPRegion(ctx)) },
+		func() { clusterName = valueOrEmpty(c.GetGCPClusterName(ctx)) },
+		func() { groupName = valueOrEmpty(c.GetGCPInstanceGroupName(ctx)) },
+	)
+
+	env.Zone = lastPathSegment(zone)
+	env.Region = lastPathSegment(region)
+	if env.Region == "" && env.Zone != "" {
+		// GCE and GKE metadata servers report the zone only
+		env.Region = env.Zone[:max(strings.LastIndex(env.Zone, "-"), 0)]
+	}
+
+	runtime := DetectServerlessRuntime()
+	switch {
+	case runtime != ServerlessNone:
+		env.Provider = serverlessProviders[runtime]
+		env.InstanceName = ""
+		env.GroupName = cmp.Or(os.Getenv("CLOUD_RUN_JOB"), os.Getenv("K_SERVICE"), os.Getenv("FUNCTION_NAME"), os.Getenv("GAE_SERVICE"))
+		if runtime == ServerlessAppEngine {
+			env.InstanceID = cmp.Or(os.Getenv("GAE_INSTANCE"), env.InstanceID)
+		}
+	case clusterName != "" || os.Getenv("KUBERNETES_SERVICE_HOST") != "":
+		env.Provider = ProviderGKE
+		env.GroupName = groupName
+	default:
+		env.Provider = ProviderGCE
+		env.GroupName = groupName
+	}
+	return env
+}
+
+// detectEC2 fills in the environment on EC2.
+func (c *Client) detectEC2(ctx context.Context, instanceID string) *Environment {
+	env := &Environment{Provider: ProviderEC2, InstanceID: instanceID}
+	parallel(
+		func() { env.AccountID = valueOrEmpty(c.GetAWSAccountID(ctx)) },
+		func() { env.Region = valueOrEmpty(c.GetAWSRegion(ctx)) },
+		func() { env.Zone = valueOrEmpty(c.GetAWSAvailabilityZone(ctx)) },
+		func() { env.InstanceName = valueOrEmpty(c.GetAWSMetadata(ctx, "/latest/meta-data/tags/instance/Name")) },
+		func() { env.GroupName = valueOrEmpty(c.GetAWSAutoScalingGroupName(ctx)) },
+	)
+	return env
+}
+
+// detectECS fills in the environment on ECS from the Task Metadata Endpoint, falling back to
+// the region in the environment. It reports whether the task metadata was read or cannot be.
+func (c *Client) detectECS(ctx context.Context) (*Environment, bool) {
+	env := &Environment{Provider: ProviderECS, Region: cmp.Or(os.Getenv("AWS_REGION"), os.Getenv("AWS_DEFAULT_REGION"))}
+	info, err := c.GetECSTaskInfo(ctx)
+	if err != nil {
+		return env, isDefinitive(err)
+	}
+	env.AccountID = info.AccountID
+	env.Region = cmp.Or(info.Region, env.Region)
+	env.Zone = info.AvailabilityZone
+	env.InstanceID = info.TaskID
+	env.InstanceName = info.ContainerName
+	env.GroupName = info.Family
+	return env, true
+}
+
+// Detect determines the runtime environment. See Client.Detect.
+func Detect(ctx context.Context) *Environment {
+	return DefaultClient().Detect(ctx)
+}
+
+// parallel runs the functions concurrently and waits for all of them to return.
+func parallel(funcs ...func()) {
+	var wg sync.WaitGroup
+	for _, f := range funcs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			f()
+		}()
+	}
+	wg.Wait()
+}
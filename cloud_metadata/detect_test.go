@@ -0,0 +1,159 @@
+package cloud_metadata
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"sync/atomic"
+	"testing"
+)
+
+func TestDetect(t *testing.T) {
+	gcpValues := map[string]string{
+		"/computeMetadata/v1/instance/id":                    "1234",
+		"/computeMetadata/v1/instance/name":                  "vm-1",
+		"/computeMetadata/v1/project/project-id":             "my-project",
+		"/computeMetadata/v1/instance/zone":                  "projects/123/zones/us-central1-a",
+		"/computeMetadata/v1/instance/attributes/created-by": "projects/123/zones/us-central1-a/instanceGroupManagers/my-mig",
+	}
+	awsValues := map[string]string{
+		"/latest/meta-data/instance-id":                             "i-123",
+		"/latest/meta-data/placement/region":                        "us-east-1",
+		"/latest/meta-data/placement/availability-zone":             "us-east-1a",
+		"/latest/meta-data/tags/instance/aws:autoscaling:groupName": "my-asg",
+		"/latest/dynamic/instance-identity/document":                `{"accountId": "111122223333"}`,
+	}
+	cloudRunValues := map[string]string{
+		"/computeMetadata/v1/instance/id":        "00abcdef",
+		"/computeMetadata/v1/project/project-id": "my-project",
+		"/computeMetadata/v1/instance/region":    "projects/123/regions/us-central1",
+	}
+
+	tests := []struct {
+		name     string
+		env      map[string]string
+		gcp      map[string]string
+		aws      map[string]string
+		expected Environment
+	}{
+		{
+			name: "GCE",
+			gcp:  gcpValues,
+			expected: Environment{Provider: ProviderGCE, ProjectID: "my-project", Region: "us-central1", Zone: "us-central1-a",
+				InstanceID: "1234", InstanceName: "vm-1", GroupName: "my-mig"},
+		},
+		{
+			name: "GKE",
+			env:  map[string]string{"KUBERNETES_SERVICE_HOST": "10.0.0.1"},
+			gcp:  gcpValues,
+			expected: Environment{Provider: ProviderGKE, ProjectID: "my-project", Region: "us-central1", Zone: "us-central1-a",
+				InstanceID: "1234", InstanceName: "vm-1", GroupName: "my-mig"},
+		},
+		{
+			name:     "Cloud Run",
+			env:      map[string]string{"K_SERVICE": "my-service"},
+			gcp:      cloudRunValues,
+			expected: Environment{Provider: ProviderCloudRun, ProjectID: "my-project", Region: "us-central1", InstanceID: "00abcdef", GroupName: "my-service"},
+		},
+		{
+			name: "EC2",
+			aws:  awsValues,
+			expected: Environment{Provider: ProviderEC2, AccountID: "111122223333", Region: "us-east-1", Zone: "us-east-1a",
+				InstanceID: "i-123", GroupName: "my-asg"},
+		},
+		{
+			name:     "none",
+			expected: Environment{Provider: ProviderNone},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clearRuntimeEnv(t)
+			for k, v := range tt.env {
+				t.Setenv(k, v)
+			}
+			opts := &ClientOptions{GCPBaseURL: "http://127.0.0.1:1", AWSBaseURL: "http://127.0.0.1:1", AzureBaseURL: "http://127.0.0.1:1"}
+			if tt.gcp != nil {
+				opts.GCPBaseURL = newFakeGCPServer(t, tt.gcp).URL
+			}
+			if tt.aws != nil {
+				opts.AWSBaseURL = newFakeAWSServer(t, tt.aws).URL
+			}
+
+			if env := NewClient(opts).Detect(context.Background()); *env != tt.expected {
+				t.Errorf("Detect() = %+v, expected %+v", *env, tt.expected)
+			}
+		})
+	}
+}
+
+func TestDetect_Memoized(t *testing.T) {
+	clearRuntimeEnv(t)
+	var requests atomic.Int64
+	gcp := newFakeGCPServer(t, map[string]string{
+		"/computeMetadata/v1/instance/id":        "1234",
+		"/computeMetadata/v1/instance/name":      "vm-1",
+		"/computeMetadata/v1/project/project-id": "my-project",
+		"/computeMetadata/v1/instance/zone":      "projects/123/zones/us-central1-a",
+	})
+	handler := gcp.Config.Handler
+	gcp.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		handler.ServeHTTP(w, r)
+	})
+	client := NewClient(&ClientOptions{GCPBaseURL: gcp.URL, AWSBaseURL: "http://127.0.0.1:1", AzureBaseURL: "http://127.0.0.1:1"})
+	ctx := context.Background()
+
+	if name := client.GetInstanceName(ctx); name != "vm-1" {
+		t.Errorf("GetInstanceName() = %q", name)
+	}
+	n := requests.Load()
+	client.Detect(ctx).InstanceName = "changed"
+	if name := client.GetInstanceName(ctx); name != "vm-1" {
+		t.Errorf("GetInstanceName() = %q after modifying a detected Environment", name)
+	}
+	if requests.Load() != n {
+		t.Errorf("expected the Environment to be memoized, got %d more requests", requests.Load()-n)
+	}
+
+	// The serverless runtime is no longer detected, so the generic resource is used
+	t.Setenv("K_SERVICE", "my-service")
+	client = NewClient(&ClientOptions{GCPBaseURL: gcp.URL, AWSBaseURL: "http://127.0.0.1:1", AzureBaseURL: "http://127.0.0.1:1"})
+	if env := client.Detect(ctx); env.Provider != ProviderCloudRun {
+		t.Fatalf("expected %s, got %s", ProviderCloudRun, env.Provider)
+	}
+	os.Unsetenv("K_SERVICE")
+	if resource := client.DetectMonitoredResource(ctx, "", nil); resource.Type != "generic_node" {
+		t.Errorf("expected generic_node, got %s", resource.Type)
+	}
+}
+
+func TestDetect_SlowMetadataServer(t *testing.T) {
+	clearRuntimeEnv(t)
+	var slow atomic.Bool
+	slow.Store(true)
+	gcp := newFakeGCPServer(t, map[string]string{
+		"/computeMetadata/v1/instance/id":   "1234",
+		"/computeMetadata/v1/instance/name": "vm-1",
+	})
+	handler := gcp.Config.Handler
+	gcp.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if slow.Load() {
+			// Slower than detectProbeTimeout
+			<-r.Context().Done()
+			return
+		}
+		handler.ServeHTTP(w, r)
+	})
+	client := NewClient(&ClientOptions{GCPBaseURL: gcp.URL, AWSBaseURL: "http://127.0.0.1:1", AzureBaseURL: "http://127.0.0.1:1"})
+	ctx := context.Background()
+
+	if env := client.Detect(ctx); env.Provider != ProviderNone {
+		t.Fatalf("expected %s while the metadata server is slow, got %s", ProviderNone, env.Provider)
+	}
+	slow.Store(false)
+	if env := client.Detect(ctx); env.Provider != ProviderGCE || env.InstanceName != "vm-1" {
+		t.Errorf("expected %s once the metadata server responds, got %+v", ProviderGCE, *env)
+	}
+}
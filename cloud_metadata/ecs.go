@@ -0,0 +1,101 @@
+package cloud_metadata
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// ecsMetadataURIEnv is set by the ECS agent in every container to the Task Metadata Endpoint v4.
+const ecsMetadataURIEnv = "ECS_CONTAINER_METADATA_URI_V4"
+
+// ErrNotECS is returned by GetECSTaskInfo when no ECS Task Metadata Endpoint is configured.
+var ErrNotECS = errors.New("not running on ECS: " + ecsMetadataURIEnv + " is not set")
+
+// ECSTaskInfo describes the ECS task and container the process runs in.
+type ECSTaskInfo struct {
+	Cluster          string
+	TaskARN          string
+	Family           string
+	Revision         string
+	AvailabilityZone string
+	ContainerName    string
+	// TaskID, Region and AccountID are parsed from TaskARN.
+	TaskID    string
+	Region    string
+	AccountID string
+}
+
+// GetECSTaskInfo returns the task and container from the ECS Task Metadata Endpoint v4.
+func (c *Client) GetECSTaskInfo(ctx context.Context) (*ECSTaskInfo, error) {
+	if c.ecsMetadataURI == "" {
+		return nil, ErrNotECS
+	}
+
+	var containerData, taskData string
+	var containerErr, taskErr error
+	parallel(
+		func() { containerData, containerErr = c.cachedGet(ctx, c.ecsMetadataURI, ecsHeader) },
+		func() { taskData, taskErr = c.cachedGet(ctx, c.ecsMetadataURI+"/task", ecsHeader) },
+	)
+	if err := errors.Join(containerErr, taskErr); err != nil {
+		return nil, err
+	}
+
+	var container struct {
+		Name string `json:"Name"`
+	}
+	if err := json.Unmarshal([]byte(containerData), &container); err != nil {
+		return nil, fmt.Errorf("failed to parse ECS container metadata: %v", err)
+	}
+	var task struct {
+		Cluster          string `json:"Cluster"`
+		TaskARN          string `json:"TaskARN"`
+		Family           string `json:"Family"`
+		Revision         string `json:"Revision"`
+		AvailabilityZone string `json:"AvailabilityZone"`
+	}
+	if err := json.Unmarshal([]byte(taskData), &task); err != nil {
+		return nil, fmt.Errorf("failed to parse ECS task metadata: %v", err)
+	}
+
+	info := &ECSTaskInfo{
+		Cluster:          task.Cluster,
+		TaskARN:          task.TaskARN,
+		Family:           task.Family,
+		Revision:         task.Revision,
+		AvailabilityZone: task.AvailabilityZone,
+		ContainerName:    container.Name,
+	}
+	// arn:aws:ecs:<region>:<account>:task/<cluster>/<task id>
+	if arn := strings.SplitN(task.TaskARN, ":", 6); len(arn) == 6 {
+		info.Region = arn[3]
+		info.AccountID = arn[4]
+		info.TaskID = lastPathSegment(arn[5])
+	}
+	// Clusters are reported by ARN on EC2 and by name on Fargate
+	info.Cluster = lastPathSegment(info.Cluster)
+	return info, nil
+}
+
+// GetECSTaskInfo returns the task and container from the ECS Task Metadata Endpoint v4.
+func GetECSTaskInfo() (*ECSTaskInfo, error) {
+	return DefaultClient().GetECSTaskInfo(context.Background())
+}
+
+// ecsCluster returns the name of the ECS cluster.
+func (c *Client) ecsCluster(ctx context.Context) (string, error) {
+	info, err := c.GetECSTaskInfo(ctx)
+	if err != nil {
+		return "", err
+	}
+	return info.Cluster, nil
+}
+
+// ecsHeader returns the headers of a Task Metadata Endpoint request, which requires none.
+func ecsHeader(context.Context) http.Header {
+	return http.Header{}
+}
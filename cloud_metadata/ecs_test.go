@@ -0,0 +1,76 @@
+package cloud_metadata
+
+import (
+	"context"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestGetECSTaskInfo(t *testing.T) {
+	clearRuntimeEnv(t)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v4/abc":
+			w.Write([]byte(`{"DockerId": "abc", "Name": "web", "DockerName": "ecs-web-1-web-abc"}`))
+		case "/v4/abc/task":
+			w.Write([]byte(`{
+				"Cluster": "arn:aws:ecs:us-west-2:111122223333:cluster/prod",
+				"TaskARN": "arn:aws:ecs:us-west-2:111122223333:task/prod/0123456789abcdef",
+				"Family": "web",
+				"Revision": "7",
+				"AvailabilityZone": "us-west-2b"
+			}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+	t.Setenv(ecsMetadataURIEnv, server.URL+"/v4/abc")
+	client := NewClient(&ClientOptions{GCPBaseURL: "http://127.0.0.1:1", AWSBaseURL: "http://127.0.0.1:1"})
+	ctx := context.Background()
+
+	info, err := client.GetECSTaskInfo(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := ECSTaskInfo{
+		Cluster:          "prod",
+		TaskARN:          "arn:aws:ecs:us-west-2:111122223333:task/prod/0123456789abcdef",
+		Family:           "web",
+		Revision:         "7",
+		AvailabilityZone: "us-west-2b",
+		ContainerName:    "web",
+		TaskID:           "0123456789abcdef",
+		Region:           "us-west-2",
+		AccountID:        "111122223333",
+	}
+	if *info != expected {
+		t.Errorf("GetECSTaskInfo() = %+v, expected %+v", *info, expected)
+	}
+
+	env := client.Detect(ctx)
+	expectedEnv := Environment{Provider: ProviderECS, AccountID: "111122223333", Region: "us-west-2", Zone: "us-west-2b",
+		InstanceID: "0123456789abcdef", InstanceName: "web", GroupName: "web"}
+	if *env != expectedEnv {
+		t.Errorf("Detect() = %+v, expected %+v", *env, expectedEnv)
+	}
+	if name := client.GetInstanceName(ctx); name != "0123456789abcdef" {
+		t.Errorf("expected the task ID as instance name, got %q", name)
+	}
+
+	resource := client.DetectMonitoredResource(ctx, "my-project", nil)
+	expectedLabels := map[string]string{"project_id": "my-project", "location": "us-west-2", "namespace": "prod",
+		"job": "web", "task_id": "0123456789abcdef"}
+	if resource.Type != "generic_task" || !maps.Equal(resource.Labels, expectedLabels) {
+		t.Errorf("unexpected resource %s %v", resource.Type, resource.Labels)
+	}
+}
+
+func TestGetECSTaskInfo_NotECS(t *testing.T) {
+	clearRuntimeEnv(t)
+	if _, err := NewClient(nil).GetECSTaskInfo(context.Background()); err != ErrNotECS {
+		t.Errorf("expected ErrNotECS, got %v", err)
+	}
+}
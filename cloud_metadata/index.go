@@ -1,11 +1,29 @@
 package cloud_metadata
 
-import "os"
+import (
+	"context"
+	"os"
+)
 
+// GetInstanceName returns the instance name on Google Cloud and Azure, the instance or task ID on AWS,
+// or the hostname when no metadata server is available
+func (c *Client) GetInstanceName(ctx context.Context) string {
+	return c.Detect(ctx).instanceName()
+}
+
+// GetInstanceName returns the instance name on Google Cloud and Azure, the instance or task ID on AWS,
+// or the hostname when no metadata server is available
 func GetInstanceName() string {
-	instanceID, err := GetAWSEC2InstanceID()
-	if err == nil && instanceID != "" {
-		return instanceID
+	return DefaultClient().GetInstanceName(context.Background())
+}
+
+// instanceName returns the name that identifies this instance in the environment.
+func (env *Environment) instanceName() string {
+	if (env.Provider.IsGCP() || env.Provider == ProviderAzure) && env.InstanceName != "" {
+		return env.InstanceName
+	}
+	if env.InstanceID != "" {
+		return env.InstanceID
 	}
 
 	hostname, err := os.Hostname()
@@ -0,0 +1,95 @@
+package cloud_metadata
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// InstanceGroupScope is the scope of a managed instance group.
+type InstanceGroupScope string
+
+const (
+	InstanceGroupZonal    InstanceGroupScope = "zonal"
+	InstanceGroupRegional InstanceGroupScope = "regional"
+)
+
+// InstanceGroup identifies the managed instance group that created an instance.
+type InstanceGroup struct {
+	Name  string
+	Scope InstanceGroupScope
+	// Location is the zone of a zonal group, or the region of a regional group.
+	Location string
+	// Project is the project number or ID that owns the group.
+	Project string
+}
+
+// InstanceGroupError reports a created-by value that does not name a managed instance group,
+// for example because the instance was created directly rather than by a group.
+type InstanceGroupError struct {
+	CreatedBy string
+}
+
+func (e *InstanceGroupError) Error() string {
+	return fmt.Sprintf("created-by %q is not a managed instance group", e.CreatedBy)
+}
+
+// ParseInstanceGroup parses a created-by attribute of the form
+// projects/<project>/zones/<zone>/instanceGroupManagers/<name> or
+// projects/<project>/regions/<region>/instanceGroupManagers/<name>.
+func ParseInstanceGroup(createdBy string) (*InstanceGroup, error) {
+	parts := strings.Split(createdBy, "/")
+	if len(parts) != 6 || parts[0] != "projects" || parts[4] != "instanceGroupManagers" ||
+		parts[1] == "" || parts[3] == "" || parts[5] == "" {
+		return nil, &InstanceGroupError{CreatedBy: createdBy}
+	}
+
+	group := &InstanceGroup{Name: parts[5], Location: parts[3], Project: parts[1]}
+	switch parts[2] {
+	case "zones":
+		group.Scope = InstanceGroupZonal
+	case "regions":
+		group.Scope = InstanceGroupRegional
+	default:
+		return nil, &InstanceGroupError{CreatedBy: createdBy}
+	}
+	return group, nil
+}
+
+// GetGCPCreatedBy returns the raw created-by attribute from the metadata server
+func (c *Client) GetGCPCreatedBy(ctx context.Context) (string, error) {
+	return c.GetGCPMetadata(ctx, "/computeMetadata/v1/instance/attributes/created-by")
+}
+
+// GetGCPInstanceGroup returns the managed instance group that created the instance
+func (c *Client) GetGCPInstanceGroup(ctx context.Context) (*InstanceGroup, error) {
+	createdBy, err := c.GetGCPCreatedBy(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return ParseInstanceGroup(createdBy)
+}
+
+// GetGCPInstanceGroupName returns the managed instance group name from the metadata server
+func (c *Client) GetGCPInstanceGroupName(ctx context.Context) (string, error) {
+	group, err := c.GetGCPInstanceGroup(ctx)
+	if err != nil {
+		return "", err
+	}
+	return group.Name, nil
+}
+
+// GetGCPCreatedBy returns the raw created-by attribute from the metadata server
+func GetGCPCreatedBy() (string, error) {
+	return DefaultClient().GetGCPCreatedBy(context.Background())
+}
+
+// GetGCPInstanceGroup returns the managed instance group that created the instance
+func GetGCPInstanceGroup() (*InstanceGroup, error) {
+	return DefaultClient().GetGCPInstanceGroup(context.Background())
+}
+
+// GetGCPInstanceGroupName returns the managed instance group name from the metadata server
+func GetGCPInstanceGroupName() (string, error) {
+	return DefaultClient().GetGCPInstanceGroupName(context.Background())
+}
@@ -0,0 +1,66 @@
+package cloud_metadata
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestGetGCPInstanceGroup(t *testing.T) {
+	tests := []struct {
+		name      string
+		createdBy string
+		expected  *InstanceGroup
+	}{
+		{
+			name:      "zonal",
+			createdBy: "projects/123/zones/us-central1-a/instanceGroupManagers/my-mig",
+			expected:  &InstanceGroup{Name: "my-mig", Scope: InstanceGroupZonal, Location: "us-central1-a", Project: "123"},
+		},
+		{
+			name:      "regional",
+			createdBy: "projects/123/regions/europe-west1/instanceGroupManagers/web",
+			expected:  &InstanceGroup{Name: "web", Scope: InstanceGroupRegional, Location: "europe-west1", Project: "123"},
+		},
+		{
+			name:      "not a group",
+			createdBy: "projects/123/zones/us-central1-a/instances/vm-1",
+		},
+		{
+			name:      "empty name",
+			createdBy: "projects/123/zones/us-central1-a/instanceGroupManagers/",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newFakeGCPServer(t, map[string]string{"/computeMetadata/v1/instance/attributes/created-by": tt.createdBy})
+			client := NewClient(&ClientOptions{GCPBaseURL: server.URL})
+
+			group, err := client.GetGCPInstanceGroup(context.Background())
+			if tt.expected == nil {
+				var groupErr *InstanceGroupError
+				if !errors.As(err, &groupErr) || groupErr.CreatedBy != tt.createdBy {
+					t.Errorf("expected InstanceGroupError, got %v, %v", group, err)
+				}
+				return
+			}
+			if err != nil || *group != *tt.expected {
+				t.Errorf("GetGCPInstanceGroup() = %+v, %v, expected %+v", group, err, *tt.expected)
+			}
+			if name, err := client.GetGCPInstanceGroupName(context.Background()); err != nil || name != tt.expected.Name {
+				t.Errorf("GetGCPInstanceGroupName() = %q, %v", name, err)
+			}
+		})
+	}
+}
+
+func TestGetGCPInstanceGroup_NoAttribute(t *testing.T) {
+	server := newFakeGCPServer(t, map[string]string{})
+	client := NewClient(&ClientOptions{GCPBaseURL: server.URL})
+
+	var groupErr *InstanceGroupError
+	if _, err := client.GetGCPInstanceGroup(context.Background()); err == nil || errors.As(err, &groupErr) {
+		t.Errorf("expected a metadata error for a missing created-by attribute, got %v", err)
+	}
+}
@@ -0,0 +1,172 @@
+package cloud_metadata
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// File locations read for Kubernetes metadata. They are variables so that tests can replace them.
+var (
+	// serviceAccountNamespaceFile holds the namespace of the pod when a service account token is mounted.
+	serviceAccountNamespaceFile = "/var/run/secrets/kubernetes.io/serviceaccount/namespace"
+	// downwardAPIDir is where a downward API volume with the files name and namespace is expected, e.g.
+	//
+	//	volumes:
+	//	- name: podinfo
+	//	  downwardAPI:
+	//	    items:
+	//	    - path: name
+	//	      fieldRef: {fieldPath: metadata.name}
+	//	    - path: namespace
+	//	      fieldRef: {fieldPath: metadata.namespace}
+	downwardAPIDir = "/etc/podinfo"
+)
+
+// KubernetesInfo holds the labels of a k8s_container monitored resource.
+type KubernetesInfo struct {
+	ClusterName     string
+	ClusterLocation string
+	Namespace       string
+	PodName         string
+	ContainerName   string
+}
+
+// KubernetesValueError reports a Kubernetes value that was not found in any of its sources.
+type KubernetesValueError struct {
+	// Value is the resource label that is missing, e.g. namespace_name.
+	Value string
+	// Sources lists where the value was looked for.
+	Sources []string
+}
+
+func (e *KubernetesValueError) Error() string {
+	return fmt.Sprintf("kubernetes %s not found; provide it with one of: %s", e.Value, strings.Join(e.Sources, "; "))
+}
+
+// GetKubernetesNamespace returns the pod namespace from the POD_NAMESPACE environment variable,
+// the downward API volume, or the service account namespace file
+func GetKubernetesNamespace() (string, error) {
+	if namespace := os.Getenv("POD_NAMESPACE"); namespace != "" {
+		return namespace, nil
+	}
+	downwardAPIFile := filepath.Join(downwardAPIDir, "namespace")
+	for _, file := range []string{downwardAPIFile, serviceAccountNamespaceFile} {
+		if namespace, err := readValueFile(file); err == nil {
+			return namespace, nil
+		}
+	}
+	return "", &KubernetesValueError{Value: "namespace_name", Sources: []string{
+		"env POD_NAMESPACE from fieldRef metadata.namespace",
+		"a downward API volume with metadata.namespace at " + downwardAPIFile,
+		"a service account token mounted at " + filepath.Dir(serviceAccountNamespaceFile),
+	}}
+}
+
+// GetKubernetesPodName returns the pod name from the POD_NAME environment variable,
+// the downward API volume, or the hostname, which Kubernetes sets to the pod name
+func GetKubernetesPodName() (string, error) {
+	if pod := os.Getenv("POD_NAME"); pod != "" {
+		return pod, nil
+	}
+	downwardAPIFile := filepath.Join(downwardAPIDir, "name")
+	if pod, err := readValueFile(downwardAPIFile); err == nil {
+		return pod, nil
+	}
+	if os.Getenv("KUBERNETES_SERVICE_HOST") != "" {
+		if hostname, err := os.Hostname(); err == nil {
+			return hostname, nil
+		}
+	}
+	return "", &KubernetesValueError{Value: "pod_name", Sources: []string{
+		"env POD_NAME from fieldRef metadata.name",
+		"a downward API volume with metadata.name at " + downwardAPIFile,
+	}}
+}
+
+// GetKubernetesContainerName returns the container name from the CONTAINER_NAME environment variable.
+// The downward API does not expose the container name, so it must be set in the pod spec
+func GetKubernetesContainerName() (string, error) {
+	if container := os.Getenv("CONTAINER_NAME"); container != "" {
+		return container, nil
+	}
+	return "", &KubernetesValueError{Value: "container_name", Sources: []string{
+		"env CONTAINER_NAME set to the name of the container in the pod spec",
+	}}
+}
+
+// GetKubernetesClusterName returns the cluster name from the CLUSTER_NAME environment variable,
+// or the cluster-name attribute of the GKE metadata server
+func (c *Client) GetKubernetesClusterName(ctx context.Context) (string, error) {
+	if cluster := os.Getenv("CLUSTER_NAME"); cluster != "" {
+		return cluster, nil
+	}
+	if cluster, err := c.GetGCPClusterName(ctx); err == nil && cluster != "" {
+		return cluster, nil
+	}
+	return "", &KubernetesValueError{Value: "cluster_name", Sources: []string{
+		"env CLUSTER_NAME",
+		"the cluster-name attribute of the GKE metadata server",
+	}}
+}
+
+// GetKubernetesClusterLocation returns the cluster location from the CLUSTER_LOCATION environment variable,
+// or the cluster-location attribute of the GKE metadata server
+func (c *Client) GetKubernetesClusterLocation(ctx context.Context) (string, error) {
+	if location := os.Getenv("CLUSTER_LOCATION"); location != "" {
+		return location, nil
+	}
+	if location, err := c.GetGCPClusterLocation(ctx); err == nil && location != "" {
+		return location, nil
+	}
+	return "", &KubernetesValueError{Value: "location", Sources: []string{
+		"env CLUSTER_LOCATION",
+		"the cluster-location attribute of the GKE metadata server",
+	}}
+}
+
+// GetKubernetesInfo returns the k8s_container resource labels. Values that are found are returned
+// even if others are missing; the error joins one KubernetesValueError per missing value.
+func (c *Client) GetKubernetesInfo(ctx context.Context) (*KubernetesInfo, error) {
+	info := &KubernetesInfo{}
+	var errs [5]error
+	parallel(
+		func() { info.ClusterName, errs[0] = c.GetKubernetesClusterName(ctx) },
+		func() { info.ClusterLocation, errs[1] = c.GetKubernetesClusterLocation(ctx) },
+	)
+	info.Namespace, errs[2] = GetKubernetesNamespace()
+	info.PodName, errs[3] = GetKubernetesPodName()
+	info.ContainerName, errs[4] = GetKubernetesContainerName()
+	return info, errors.Join(errs[:]...)
+}
+
+// GetKubernetesClusterName returns the cluster name. See Client.GetKubernetesClusterName.
+func GetKubernetesClusterName() (string, error) {
+	return DefaultClient().GetKubernetesClusterName(context.Background())
+}
+
+// GetKubernetesClusterLocation returns the cluster location. See Client.GetKubernetesClusterLocation.
+func GetKubernetesClusterLocation() (string, error) {
+	return DefaultClient().GetKubernetesClusterLocation(context.Background())
+}
+
+// GetKubernetesInfo returns the k8s_container resource labels. See Client.GetKubernetesInfo.
+func GetKubernetesInfo() (*KubernetesInfo, error) {
+	return DefaultClient().GetKubernetesInfo(context.Background())
+}
+
+// readValueFile returns the trimmed contents of a file, or an error if it is missing or empty.
+func readValueFile(file string) (string, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return "", err
+	}
+	value := strings.TrimSpace(string(data))
+	if value == "" {
+		return "", fmt.Errorf("%s is empty", file)
+	}
+	return value, nil
+}
@@ -0,0 +1,86 @@
+package cloud_metadata
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// useKubernetesFiles points the Kubernetes file locations at a temporary directory and clears the
+// environment variables they are read from.
+func useKubernetesFiles(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	savedDownwardAPIDir, savedNamespaceFile := downwardAPIDir, serviceAccountNamespaceFile
+	downwardAPIDir = filepath.Join(dir, "podinfo")
+	serviceAccountNamespaceFile = filepath.Join(dir, "serviceaccount", "namespace")
+	t.Cleanup(func() {
+		downwardAPIDir, serviceAccountNamespaceFile = savedDownwardAPIDir, savedNamespaceFile
+	})
+	clearRuntimeEnv(t)
+	for _, name := range []string{"POD_NAMESPACE", "POD_NAME", "CONTAINER_NAME", "CLUSTER_NAME", "CLUSTER_LOCATION"} {
+		t.Setenv(name, "")
+	}
+	return dir
+}
+
+func writeFile(t *testing.T, file, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(file), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(file, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestGetKubernetesInfo(t *testing.T) {
+	useKubernetesFiles(t)
+	writeFile(t, serviceAccountNamespaceFile, "prod\n")
+	writeFile(t, filepath.Join(downwardAPIDir, "name"), "web-7d9f")
+	t.Setenv("CONTAINER_NAME", "app")
+	server := newFakeGCPServer(t, map[string]string{
+		"/computeMetadata/v1/instance/attributes/cluster-name":     "my-cluster",
+		"/computeMetadata/v1/instance/attributes/cluster-location": "us-central1",
+	})
+
+	info, err := NewClient(&ClientOptions{GCPBaseURL: server.URL}).GetKubernetesInfo(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := KubernetesInfo{ClusterName: "my-cluster", ClusterLocation: "us-central1", Namespace: "prod", PodName: "web-7d9f", ContainerName: "app"}
+	if *info != expected {
+		t.Errorf("GetKubernetesInfo() = %+v, expected %+v", *info, expected)
+	}
+
+	// Environment variables take precedence over files
+	t.Setenv("POD_NAMESPACE", "staging")
+	if namespace, _ := GetKubernetesNamespace(); namespace != "staging" {
+		t.Errorf("expected namespace staging, got %q", namespace)
+	}
+}
+
+func TestGetKubernetesInfo_Missing(t *testing.T) {
+	useKubernetesFiles(t)
+	t.Setenv("CLUSTER_NAME", "my-cluster")
+	client := NewClient(&ClientOptions{GCPBaseURL: "http://127.0.0.1:1"})
+
+	info, err := client.GetKubernetesInfo(context.Background())
+	if info.ClusterName != "my-cluster" {
+		t.Errorf("expected the values found to be returned, got %+v", *info)
+	}
+	var missing []string
+	for _, err := range err.(interface{ Unwrap() []error }).Unwrap() {
+		var valueErr *KubernetesValueError
+		if !errors.As(err, &valueErr) {
+			t.Fatalf("expected KubernetesValueError, got %v", err)
+		}
+		missing = append(missing, valueErr.Value)
+	}
+	if len(missing) != 4 || missing[0] != "location" || missing[1] != "namespace_name" ||
+		missing[2] != "pod_name" || missing[3] != "container_name" {
+		t.Errorf("unexpected missing values %v", missing)
+	}
+}
@@ -0,0 +1,148 @@
+package cloud_metadata
+
+import (
+	"cmp"
+	"context"
+	"strings"
+
+	"google.golang.org/genproto/googleapis/api/monitoredres"
+)
+
+// ResourceOptions configures the fallback resource returned by DetectMonitoredResource.
+type ResourceOptions struct {
+	// Location is the location label of generic_node and generic_task resources.
+	// Defaults to the detected region, or "global".
+	Location string
+	// Namespace is the namespace label of generic_node and generic_task resources.
+	Namespace string
+	// Job selects a generic_task fallback resource with this job label instead of generic_node.
+	Job string
+}
+
+// DetectMonitoredResource detects the runtime and returns the matching monitored resource:
+// cloud_run_revision on Cloud Run services, k8s_container on GKE, gce_instance on Compute Engine,
+// aws_ec2_instance on EC2 when the instance metadata can be read, the resource of ServerlessInfo on other serverless runtimes,
+// generic_task for ECS tasks, and generic_node or generic_task otherwise. On Azure the generic resource
+// has the VM name as node_id or task_id, the VM location and, by default, the resource group as namespace.
+// The runtime is determined with Detect. If projectID is empty, the project of the GCP metadata
+// server is used when available.
+// opts may be nil.
+func (c *Client) DetectMonitoredResource(ctx context.Context, projectID string, opts *ResourceOptions) *monitoredres.MonitoredResource {
+	// Set defaults if nil
+	if opts == nil {
+		opts = &ResourceOptions{}
+	}
+
+	env := c.Detect(ctx)
+	if projectID == "" {
+		projectID = env.ProjectID
+	}
+
+	switch env.Provider {
+	case ProviderCloudRun, ProviderCloudRunJob, ProviderCloudFunctions, ProviderAppEngine:
+		// Missing values are left empty. Without the runtime's environment variables, use the generic resource
+		if info, _ := c.GetServerlessInfo(ctx); info != nil {
+			return &monitoredres.MonitoredResource{
+				Type:   info.ResourceType(),
+				Labels: info.ResourceLabels(projectID, opts.Namespace),
+			}
+		}
+	case ProviderGKE:
+		// Missing values are left empty, which Cloud Monitoring accepts
+		info, _ := c.GetKubernetesInfo(ctx)
+		return &monitoredres.MonitoredResource{
+			Type: "k8s_container",
+			Labels: map[string]string{
+				"project_id":     projectID,
+				"location":       info.ClusterLocation,
+				"cluster_name":   info.ClusterName,
+				"namespace_name": info.Namespace,
+				"pod_name":       info.PodName,
+				"container_name": info.ContainerName,
+			},
+		}
+	case ProviderGCE:
+		return &monitoredres.MonitoredResource{
+			Type: "gce_instance",
+			Labels: map[string]string{
+				"project_id":  projectID,
+				"instance_id": env.InstanceID,
+				"zone":        env.Zone,
+			},
+		}
+	case ProviderEC2:
+		// On an instance whose IMDS is misconfigured, the instance is unknown. Use the generic resource
+		if env.InstanceID != "" {
+			return &monitoredres.MonitoredResource{
+				Type: "aws_ec2_instance",
+				Labels: map[string]string{
+					"project_id":  projectID,
+					"instance_id": env.InstanceID,
+					"region":      "aws:" + env.Region,
+					"aws_account": env.AccountID,
+				},
+			}
+		}
+	case ProviderECS:
+		return &monitoredres.MonitoredResource{
+			Type: "generic_task",
+			Labels: map[string]string{
+				"project_id": projectID,
+				"location":   cmp.Or(opts.Location, env.Region, "global"),
+				"namespace":  cmp.Or(opts.Namespace, valueOrEmpty(c.ecsCluster(ctx))),
+				"job":        cmp.Or(opts.Job, env.GroupName),
+				"task_id":    env.InstanceID,
+			},
+		}
+	}
+
+	location := cmp.Or(opts.Location, env.Region, "global")
+	namespace := opts.Namespace
+	if env.Provider == ProviderAzure && namespace == "" {
+		// Azure VM names are unique within a resource group
+		if info, err := c.GetAzureInstanceInfo(ctx); err == nil {
+			namespace = info.ResourceGroup
+		}
+	}
+	if opts.Job != "" {
+		return &monitoredres.MonitoredResource{
+			Type: "generic_task",
+			Labels: map[string]string{
+				"project_id": projectID,
+				"location":   location,
+				"namespace":  namespace,
+				"job":        opts.Job,
+				"task_id":    env.instanceName(),
+			},
+		}
+	}
+	return &monitoredres.MonitoredResource{
+		Type: "generic_node",
+		Labels: map[string]string{
+			"project_id": projectID,
+			"location":   location,
+			"namespace":  namespace,
+			"node_id":    env.instanceName(),
+		},
+	}
+}
+
+// DetectMonitoredResource detects the runtime and returns the matching monitored resource.
+// See Client.DetectMonitoredResource.
+func DetectMonitoredResource(projectID string, opts *ResourceOptions) *monitoredres.MonitoredResource {
+	return DefaultClient().DetectMonitoredResource(context.Background(), projectID, opts)
+}
+
+// valueOrEmpty returns the value of a getter, or an empty string if it failed.
+func valueOrEmpty(value string, err error) string {
+	if err != nil {
+		return ""
+	}
+	return value
+}
+
+// lastPathSegment returns the part of a metadata value after the last slash,
+// e.g. us-central1-a for projects/123/zones/us-central1-a.
+func lastPathSegment(value string) string {
+	return value[strings.LastIndex(value, "/")+1:]
+}
@@ -0,0 +1,149 @@
+package cloud_metadata
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+)
+
+// ServerlessRuntime identifies a Google Cloud serverless runtime.
+type ServerlessRuntime string
+
+const (
+	ServerlessNone            ServerlessRuntime = ""
+	ServerlessCloudRunService ServerlessRuntime = "cloud_run_service"
+	ServerlessCloudRunJob     ServerlessRuntime = "cloud_run_job"
+	ServerlessCloudFunction   ServerlessRuntime = "cloud_function"
+	ServerlessAppEngine       ServerlessRuntime = "app_engine"
+)
+
+// ErrNotServerless is returned by GetServerlessInfo when no serverless runtime is detected.
+var ErrNotServerless = errors.New("not running on Cloud Run, Cloud Functions or App Engine")
+
+// ServerlessInfo describes a serverless workload. Fields that do not apply to the runtime are empty.
+type ServerlessInfo struct {
+	Runtime    ServerlessRuntime
+	ProjectID  string
+	Region     string
+	InstanceID string
+	// Service is the Cloud Run service, the function, or the App Engine service.
+	Service string
+	// Revision is the Cloud Run or function revision, or the App Engine version.
+	Revision string
+	// Configuration is the Cloud Run configuration.
+	Configuration string
+	// Job, Execution and TaskIndex identify a Cloud Run job task.
+	Job       string
+	Execution string
+	TaskIndex string
+}
+
+// DetectServerlessRuntime returns the serverless runtime identified by the environment variables it sets,
+// or ServerlessNone. Functions are checked before Cloud Run services, since functions also set K_SERVICE.
+func DetectServerlessRuntime() ServerlessRuntime {
+	switch {
+	case os.Getenv("CLOUD_RUN_JOB") != "":
+		return ServerlessCloudRunJob
+	case os.Getenv("FUNCTION_TARGET") != "" || os.Getenv("FUNCTION_NAME") != "":
+		return ServerlessCloudFunction
+	case os.Getenv("K_SERVICE") != "":
+		return ServerlessCloudRunService
+	case os.Getenv("GAE_SERVICE") != "":
+		return ServerlessAppEngine
+	default:
+		return ServerlessNone
+	}
+}
+
+// GetServerlessInfo returns the serverless workload from the environment, and its project, region
+// and instance ID from the metadata server. Values that are found are returned even if metadata
+// lookups fail; the error joins the failures. It returns ErrNotServerless outside serverless runtimes.
+func (c *Client) GetServerlessInfo(ctx context.Context) (*ServerlessInfo, error) {
+	runtime := DetectServerlessRuntime()
+	if runtime == ServerlessNone {
+		return nil, ErrNotServerless
+	}
+
+	info := &ServerlessInfo{Runtime: runtime}
+	switch runtime {
+	case ServerlessCloudRunJob:
+		info.Job = os.Getenv("CLOUD_RUN_JOB")
+		info.Execution = os.Getenv("CLOUD_RUN_EXECUTION")
+		info.TaskIndex = os.Getenv("CLOUD_RUN_TASK_INDEX")
+	case ServerlessCloudFunction:
+		// Newer runtimes set K_SERVICE and K_REVISION, older ones FUNCTION_NAME
+		info.Service = cmp.Or(os.Getenv("K_SERVICE"), os.Getenv("FUNCTION_NAME"))
+		info.Revision = os.Getenv("K_REVISION")
+	case ServerlessCloudRunService:
+		info.Service = os.Getenv("K_SERVICE")
+		info.Revision = os.Getenv("K_REVISION")
+		info.Configuration = os.Getenv("K_CONFIGURATION")
+	case ServerlessAppEngine:
+		info.Service = os.Getenv("GAE_SERVICE")
+		info.Revision = os.Getenv("GAE_VERSION")
+		info.InstanceID = os.Getenv("GAE_INSTANCE")
+	}
+
+	var projectErr, regionErr, instanceErr error
+	var region string
+	parallel(
+		func() { info.ProjectID, projectErr = c.GetGCPProjectID(ctx) },
+		func() { region, regionErr = c.GetGCPRegion(ctx) },
+		func() {
+			if info.InstanceID == "" {
+				info.InstanceID, instanceErr = c.GetGCPInstanceID(ctx)
+			}
+		},
+	)
+	info.Region = lastPathSegment(region)
+	if projectErr != nil {
+		info.ProjectID = os.Getenv("GOOGLE_CLOUD_PROJECT")
+	}
+	return info, errors.Join(projectErr, regionErr, instanceErr)
+}
+
+// GetServerlessInfo returns the serverless workload. See Client.GetServerlessInfo.
+func GetServerlessInfo() (*ServerlessInfo, error) {
+	return DefaultClient().GetServerlessInfo(context.Background())
+}
+
+// ResourceType returns the monitored resource type for the workload: cloud_run_revision for Cloud Run
+// services and functions deployed on Cloud Run, and generic_task for jobs, older functions and App Engine,
+// whose own resource types do not accept custom metrics.
+func (info *ServerlessInfo) ResourceType() string {
+	if info.Runtime == ServerlessCloudRunService || (info.Runtime == ServerlessCloudFunction && info.Revision != "") {
+		return "cloud_run_revision"
+	}
+	return "generic_task"
+}
+
+// ResourceLabels returns the labels of the monitored resource of type ResourceType.
+// projectID overrides the detected project if not empty. For generic_task, namespace overrides
+// the default namespace, which is the name of the runtime.
+func (info *ServerlessInfo) ResourceLabels(projectID, namespace string) map[string]string {
+	projectID = cmp.Or(projectID, info.ProjectID)
+	if info.ResourceType() == "cloud_run_revision" {
+		return map[string]string{
+			"project_id":         projectID,
+			"service_name":       info.Service,
+			"revision_name":      info.Revision,
+			"configuration_name": cmp.Or(info.Configuration, info.Service),
+			"location":           info.Region,
+		}
+	}
+
+	labels := map[string]string{
+		"project_id": projectID,
+		"location":   info.Region,
+		"namespace":  cmp.Or(namespace, string(info.Runtime)),
+		"job":        info.Service,
+		"task_id":    info.InstanceID,
+	}
+	if info.Runtime == ServerlessCloudRunJob {
+		labels["job"] = info.Job
+		labels["task_id"] = fmt.Sprintf("%s-%s", info.Execution, cmp.Or(info.TaskIndex, "0"))
+	}
+	return labels
+}
@@ -0,0 +1,90 @@
+package cloud_metadata
+
+import (
+	"context"
+	"maps"
+	"testing"
+)
+
+func TestServerlessResource(t *testing.T) {
+	metadata := map[string]string{
+		"/computeMetadata/v1/instance/id":        "00abcdef",
+		"/computeMetadata/v1/project/project-id": "my-project",
+		"/computeMetadata/v1/instance/region":    "projects/123/regions/us-central1",
+	}
+
+	tests := []struct {
+		name           string
+		env            map[string]string
+		provider       Provider
+		resourceType   string
+		resourceLabels map[string]string
+	}{
+		{
+			name:         "Cloud Run service",
+			env:          map[string]string{"K_SERVICE": "api", "K_REVISION": "api-00001-abc", "K_CONFIGURATION": "api"},
+			provider:     ProviderCloudRun,
+			resourceType: "cloud_run_revision",
+			resourceLabels: map[string]string{"project_id": "my-project", "service_name": "api", "revision_name": "api-00001-abc",
+				"configuration_name": "api", "location": "us-central1"},
+		},
+		{
+			name:         "Cloud Run job",
+			env:          map[string]string{"CLOUD_RUN_JOB": "backfill", "CLOUD_RUN_EXECUTION": "backfill-x7k2p", "CLOUD_RUN_TASK_INDEX": "3"},
+			provider:     ProviderCloudRunJob,
+			resourceType: "generic_task",
+			resourceLabels: map[string]string{"project_id": "my-project", "location": "us-central1", "namespace": "cloud_run_job",
+				"job": "backfill", "task_id": "backfill-x7k2p-3"},
+		},
+		{
+			name:         "Cloud Functions on Cloud Run",
+			env:          map[string]string{"K_SERVICE": "resize", "K_REVISION": "resize-00002-xyz", "FUNCTION_TARGET": "Resize"},
+			provider:     ProviderCloudFunctions,
+			resourceType: "cloud_run_revision",
+			resourceLabels: map[string]string{"project_id": "my-project", "service_name": "resize", "revision_name": "resize-00002-xyz",
+				"configuration_name": "resize", "location": "us-central1"},
+		},
+		{
+			name:         "Cloud Functions 1st gen",
+			env:          map[string]string{"FUNCTION_NAME": "resize"},
+			provider:     ProviderCloudFunctions,
+			resourceType: "generic_task",
+			resourceLabels: map[string]string{"project_id": "my-project", "location": "us-central1", "namespace": "cloud_function",
+				"job": "resize", "task_id": "00abcdef"},
+		},
+		{
+			name:         "App Engine",
+			env:          map[string]string{"GAE_SERVICE": "default", "GAE_VERSION": "v1", "GAE_INSTANCE": "aef-default-v1-abcd"},
+			provider:     ProviderAppEngine,
+			resourceType: "generic_task",
+			resourceLabels: map[string]string{"project_id": "my-project", "location": "us-central1", "namespace": "app_engine",
+				"job": "default", "task_id": "aef-default-v1-abcd"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clearRuntimeEnv(t)
+			for k, v := range tt.env {
+				t.Setenv(k, v)
+			}
+			client := NewClient(&ClientOptions{GCPBaseURL: newFakeGCPServer(t, metadata).URL, AWSBaseURL: "http://127.0.0.1:1", AzureBaseURL: "http://127.0.0.1:1"})
+			ctx := context.Background()
+
+			if env := client.Detect(ctx); env.Provider != tt.provider {
+				t.Errorf("expected provider %s, got %s", tt.provider, env.Provider)
+			}
+			resource := client.DetectMonitoredResource(ctx, "", nil)
+			if resource.Type != tt.resourceType || !maps.Equal(resource.Labels, tt.resourceLabels) {
+				t.Errorf("unexpected resource %s %v, expected %s %v", resource.Type, resource.Labels, tt.resourceType, tt.resourceLabels)
+			}
+		})
+	}
+}
+
+func TestGetServerlessInfo_NotServerless(t *testing.T) {
+	clearRuntimeEnv(t)
+	if _, err := NewClient(nil).GetServerlessInfo(context.Background()); err != ErrNotServerless {
+		t.Errorf("expected ErrNotServerless, got %v", err)
+	}
+}
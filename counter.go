@@ -19,6 +19,7 @@ type StaticCounter struct {
 	Name   string
 	Labels map[string]string
 	value  int64
+	cells  *counterCells // Non-nil for sharded counters
 }
 
 // NewStaticCounter creates a new StaticCounter with the given name and labels.
@@ -31,15 +32,22 @@ func NewStaticCounter(name string, labels map[string]string) *StaticCounter {
 
 // Inc increments the counter by 1. The labelValues parameter is ignored for static counters.
 func (c *StaticCounter) Inc(labelValues ...string) {
-	atomic.AddInt64(&c.value, 1)
+	c.Add(1)
 }
 
 // Add increments the counter by n. The labelValues parameter is ignored for static counters.
 func (c *StaticCounter) Add(n int64, labelValues ...string) {
+	if c.cells != nil {
+		c.cells.add(n)
+		return
+	}
 	atomic.AddInt64(&c.value, n)
 }
 
 // Value returns the current counter value.
 func (c *StaticCounter) Value() int64 {
+	if c.cells != nil {
+		return c.cells.sum()
+	}
 	return atomic.LoadInt64(&c.value)
 }
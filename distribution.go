@@ -1,7 +1,11 @@
 package gcpmetrics
 
 import (
+	"math"
+	"math/rand/v2"
+	"runtime"
 	"sync"
+	"sync/atomic"
 )
 
 // Distribution is the public interface for distributions.
@@ -21,6 +25,10 @@ type DistributionBuckets struct {
 
 // StaticDistribution is a distribution with fixed labels defined at creation time.
 // It ignores any labelValues passed to Update method.
+//
+// Update is lock-free. Samples are recorded into the current distributionState using
+// per-bucket atomics and per-shard accumulators. GetAndClear swaps in the spare state,
+// waits for in-flight updates to the previous state to finish, and merges its shards.
 type StaticDistribution struct {
 	Name       string
 	Unit       string
@@ -28,66 +36,169 @@ type StaticDistribution struct {
 	Step       int64
 	NumBuckets int
 	Labels     map[string]string
-	value      DistributionBuckets
-	mu         sync.Mutex
+	current    atomic.Pointer[distributionState]
+	spare      *distributionState // Guarded by clearMu
+	clearMu    sync.Mutex
+}
+
+// distributionState accumulates samples for one collection interval.
+type distributionState struct {
+	buckets []atomic.Int64
+	shards  []distributionShard
+	mask    uint32
+}
+
+// unsetShift marks a distributionShard whose shift has not been chosen yet.
+const unsetShift = math.MinInt64
+
+// distributionShard accumulates count, shifted sum and shifted sum of squares for a subset of updates.
+// Values are shifted by the first value recorded in the shard, an estimate of the mean, so that the
+// sum of squared deviations can be recovered without catastrophic cancellation.
+type distributionShard struct {
+	writers atomic.Int64  // Updates in progress on this shard
+	shift   atomic.Int64  // First value recorded in the shard, or unsetShift
+	count   atomic.Int64  // Number of samples
+	sum     atomic.Uint64 // Sum of (value - shift) as float64 bits
+	sumSq   atomic.Uint64 // Sum of (value - shift)^2 as float64 bits
+	_       [cacheLineSize - 40]byte
 }
 
 // NewStaticDistribution creates a new StaticDistribution with the given name, unit, step, numBuckets, and labels.
 // Unit format is documented at: https://cloud.google.com/monitoring/api/ref_v3/rest/v3/projects.metricDescriptors
 func NewStaticDistribution(name, unit string, step, numBuckets int, labels map[string]string) *StaticDistribution {
-	return &StaticDistribution{
+	d := &StaticDistribution{
 		Name:       name,
 		Unit:       unit,
 		Offset:     0,
 		Step:       int64(step),
 		NumBuckets: numBuckets,
 		Labels:     labels,
-		value: DistributionBuckets{
-			// Allocate numBuckets + 2 to account for underflow (bucket 0) and overflow (last bucket)
-			Buckets: make([]int64, numBuckets+2),
-		},
 	}
+	d.current.Store(d.newState())
+	d.spare = d.newState()
+	return d
+}
+
+// newState allocates an empty distributionState for this distribution.
+func (d *StaticDistribution) newState() *distributionState {
+	numShards := shardCount()
+	state := &distributionState{
+		// Allocate numBuckets + 2 to account for underflow (bucket 0) and overflow (last bucket)
+		buckets: make([]atomic.Int64, d.NumBuckets+2),
+		shards:  make([]distributionShard, numShards),
+		mask:    uint32(numShards - 1),
+	}
+	state.reset()
+	return state
 }
 
 // Update records a value in the distribution. The labelValues parameter is ignored for static distributions.
 func (d *StaticDistribution) Update(value int64, labelValues ...string) {
-	d.mu.Lock()
-	defer d.mu.Unlock()
+	var state *distributionState
+	var shard *distributionShard
+	for {
+		state = d.current.Load()
+		shard = &state.shards[rand.Uint32()&state.mask]
+		shard.writers.Add(1)
+		// Re-check that the state was not swapped out before we registered as a writer
+		if d.current.Load() == state {
+			break
+		}
+		shard.writers.Add(-1)
+	}
 
 	// Update bucket
-	bucket := d.bucketForValue(value)
-	d.value.Buckets[bucket] += 1
-
-	// Update numSamples, mean and M2 using Welford's method for accumulating the sum of squared deviations.
-	d.value.NumSamples += 1
-	delta := float64(value) - d.value.Mean
-	d.value.Mean = d.value.Mean + (delta / float64(d.value.NumSamples))
-	d.value.SumOfSquaredDeviation = d.value.SumOfSquaredDeviation + delta*(float64(value)-d.value.Mean)
+	state.buckets[d.bucketForValue(value)].Add(1)
+
+	// Update numSamples, shifted sum and shifted sum of squares
+	shift := shard.shift.Load()
+	if shift == unsetShift {
+		shard.shift.CompareAndSwap(unsetShift, max(value, unsetShift+1))
+		shift = shard.shift.Load()
+	}
+	// Computed in float64 so that widely spread samples cannot overflow
+	delta := float64(value) - float64(shift)
+	shard.count.Add(1)
+	addFloat64(&shard.sum, delta)
+	addFloat64(&shard.sumSq, delta*delta)
+
+	shard.writers.Add(-1)
 }
 
 // GetAndClear returns the current distribution data and resets the distribution.
 func (d *StaticDistribution) GetAndClear() *DistributionBuckets {
-	d.mu.Lock()
-	defer d.mu.Unlock()
+	d.clearMu.Lock()
+	defer d.clearMu.Unlock()
+
+	// Swap in the spare state and wait for updates still writing to the old one
+	state := d.current.Swap(d.spare)
+	for i := range state.shards {
+		for state.shards[i].writers.Load() != 0 {
+			runtime.Gosched()
+		}
+	}
+
+	result := state.merge()
+	state.reset()
+	d.spare = state
+	return result
+}
+
+// addFloat64 atomically adds v to the float64 stored as bits in u.
+func addFloat64(u *atomic.Uint64, v float64) {
+	for {
+		old := u.Load()
+		if u.CompareAndSwap(old, math.Float64bits(math.Float64frombits(old)+v)) {
+			return
+		}
+	}
+}
 
-	// Make a copy
+// merge combines the buckets and shard accumulators into a DistributionBuckets.
+// It must only be called once no updates are in progress on the state.
+func (s *distributionState) merge() *DistributionBuckets {
 	result := &DistributionBuckets{
-		Buckets:               make([]int64, len(d.value.Buckets)),
-		NumSamples:            d.value.NumSamples,
-		Mean:                  d.value.Mean,
-		SumOfSquaredDeviation: d.value.SumOfSquaredDeviation,
+		Buckets: make([]int64, len(s.buckets)),
+	}
+	for i := range s.buckets {
+		result.Buckets[i] = s.buckets[i].Load()
 	}
-	copy(result.Buckets, d.value.Buckets)
 
-	// Clear
-	clear(d.value.Buckets)
-	d.value.NumSamples = 0
-	d.value.Mean = 0
-	d.value.SumOfSquaredDeviation = 0
+	// Combine the per-shard mean and M2 using Chan et al.'s parallel algorithm
+	for i := range s.shards {
+		shard := &s.shards[i]
+		count := shard.count.Load()
+		if count == 0 {
+			continue
+		}
+		n := float64(count)
+		sum := math.Float64frombits(shard.sum.Load())
+		shiftedMean := sum / n
+		mean := float64(shard.shift.Load()) + shiftedMean
+		m2 := max(0, math.Float64frombits(shard.sumSq.Load())-sum*shiftedMean)
 
+		total := result.NumSamples + count
+		delta := mean - result.Mean
+		result.Mean += delta * n / float64(total)
+		result.SumOfSquaredDeviation += m2 + delta*delta*float64(result.NumSamples)*n/float64(total)
+		result.NumSamples = total
+	}
 	return result
 }
 
+// reset clears all buckets and accumulators.
+func (s *distributionState) reset() {
+	for i := range s.buckets {
+		s.buckets[i].Store(0)
+	}
+	for i := range s.shards {
+		s.shards[i].shift.Store(unsetShift)
+		s.shards[i].count.Store(0)
+		s.shards[i].sum.Store(0)
+		s.shards[i].sumSq.Store(0)
+	}
+}
+
 // BucketBounds returns the bucket boundaries for this distribution.
 func (d *StaticDistribution) BucketBounds() []float64 {
 	bucketBounds := make([]float64, d.NumBuckets+1)
@@ -0,0 +1,149 @@
+package gcpmetrics
+
+import (
+	"math"
+	"math/rand/v2"
+	"sync"
+	"testing"
+)
+
+func TestStaticDistribution_ConcurrentUpdate(t *testing.T) {
+	dist := NewStaticDistribution("latency", "ms", 100, 10, nil)
+
+	const numGoroutines = 50
+	const updatesPerGoroutine = 1000
+	values := make([][]int64, numGoroutines)
+	for i := range values {
+		values[i] = make([]int64, updatesPerGoroutine)
+		for j := range values[i] {
+			values[i][j] = 5_000_000 + rand.Int64N(1500)
+		}
+	}
+
+	var wg sync.WaitGroup
+	for _, vals := range values {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for _, v := range vals {
+				dist.Update(v)
+			}
+		}()
+	}
+	wg.Wait()
+
+	// Reference result using Welford's method
+	var n int64
+	var mean, m2 float64
+	for _, vals := range values {
+		for _, v := range vals {
+			n++
+			delta := float64(v) - mean
+			mean += delta / float64(n)
+			m2 += delta * (float64(v) - mean)
+		}
+	}
+
+	result := dist.GetAndClear()
+	if result.NumSamples != n {
+		t.Errorf("expected %d samples, got %d", n, result.NumSamples)
+	}
+	if math.Abs(result.Mean-mean) > 1e-6 {
+		t.Errorf("expected mean %f, got %f", mean, result.Mean)
+	}
+	if math.Abs(result.SumOfSquaredDeviation-m2)/m2 > 1e-9 {
+		t.Errorf("expected sum of squared deviation %f, got %f", m2, result.SumOfSquaredDeviation)
+	}
+	if overflow := result.Buckets[len(result.Buckets)-1]; overflow != n {
+		t.Errorf("expected %d samples in the overflow bucket, got %d", n, overflow)
+	}
+
+	if cleared := dist.GetAndClear(); cleared.NumSamples != 0 || cleared.Mean != 0 || cleared.SumOfSquaredDeviation != 0 {
+		t.Errorf("expected distribution to be cleared, got %+v", cleared)
+	}
+}
+
+func TestStaticDistribution_GetAndClearDuringUpdates(t *testing.T) {
+	dist := NewStaticDistribution("latency", "ms", 10, 10, nil)
+
+	var wg sync.WaitGroup
+	for range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range 10_000 {
+				dist.Update(42)
+			}
+		}()
+	}
+
+	var total int64
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	for finished := false; !finished; {
+		select {
+		case <-done:
+			finished = true
+		default:
+		}
+		result := dist.GetAndClear()
+		var bucketTotal int64
+		for _, b := range result.Buckets {
+			bucketTotal += b
+		}
+		if bucketTotal != result.NumSamples {
+			t.Fatalf("buckets hold %d samples, expected %d", bucketTotal, result.NumSamples)
+		}
+		if result.NumSamples > 0 && (result.Mean != 42 || result.SumOfSquaredDeviation != 0) {
+			t.Fatalf("unexpected snapshot %+v", result)
+		}
+		total += result.NumSamples
+	}
+
+	if total != 80_000 {
+		t.Errorf("expected 80000 samples across snapshots, got %d", total)
+	}
+}
+
+func TestStaticDistribution_ExtremeValues(t *testing.T) {
+	dist := NewStaticDistribution("latency", "ms", 100, 10, nil)
+
+	// The spread between these samples overflows int64
+	values := []int64{math.MinInt64, math.MaxInt64, math.MinInt64, math.MaxInt64, 1 << 62, -(1 << 62)}
+	for _, v := range values {
+		dist.Update(v)
+	}
+
+	var mean, m2 float64
+	for _, v := range values {
+		mean += float64(v) / float64(len(values))
+	}
+	for _, v := range values {
+		m2 += (float64(v) - mean) * (float64(v) - mean)
+	}
+
+	result := dist.GetAndClear()
+	if result.NumSamples != int64(len(values)) {
+		t.Errorf("expected %d samples, got %d", len(values), result.NumSamples)
+	}
+	if math.Abs(result.Mean-mean) > 1e-9*math.MaxInt64 {
+		t.Errorf("expected mean %g, got %g", mean, result.Mean)
+	}
+	if math.Abs(result.SumOfSquaredDeviation-m2)/m2 > 1e-9 {
+		t.Errorf("expected sum of squared deviation %g, got %g", m2, result.SumOfSquaredDeviation)
+	}
+}
+
+func BenchmarkStaticDistribution_Update(b *testing.B) {
+	dist := NewStaticDistribution("latency", "ms", 100, 10, nil)
+	b.RunParallel(func(pb *testing.PB) {
+		var v int64
+		for pb.Next() {
+			dist.Update(v % 1000)
+			v++
+		}
+	})
+}
@@ -14,17 +14,28 @@ type DynamicCounter struct {
 	staticLabels map[string]string
 	labelKeys    []string
 	registry     *LabelRegistry[*StaticCounter]
+	labelArityChecker
 }
 
 // NewDynamicCounter creates a new DynamicCounter with the given name, static labels, and dynamic label keys.
 // Static labels are fixed at creation time and included in all emitted metrics.
 // Dynamic label keys define which labels will have values provided at runtime.
 func NewDynamicCounter(name string, staticLabels map[string]string, labelKeys ...string) *DynamicCounter {
+	return newDynamicCounter(name, staticLabels, NewStaticCounter, labelKeys)
+}
+
+// newDynamicCounter creates a DynamicCounter that uses newCounter to create the counter for each label combination.
+func newDynamicCounter(
+	name string,
+	staticLabels map[string]string,
+	newCounter func(name string, labels map[string]string) *StaticCounter,
+	labelKeys []string,
+) *DynamicCounter {
 	// Handle nil staticLabels gracefully
 	if staticLabels == nil {
 		staticLabels = make(map[string]string)
 	}
-	return &DynamicCounter{
+	dc := &DynamicCounter{
 		Name:         name,
 		staticLabels: staticLabels,
 		labelKeys:    labelKeys,
@@ -34,26 +45,43 @@ func NewDynamicCounter(name string, staticLabels map[string]string, labelKeys ..
 			maps.Copy(labels, staticLabels)
 			dynamicLabels := labelValuesToMap(labelKeys, vals)
 			maps.Copy(labels, dynamicLabels)
-			return NewStaticCounter(name, labels)
+			return newCounter(name, labels)
 		}),
 	}
+	dc.labelArityChecker.init(name, labelKeys)
+	return dc
 }
 
 // Inc increments the counter by 1 for the given label values.
 func (dc *DynamicCounter) Inc(labelValues ...string) {
+	if !dc.check(labelValues) {
+		return
+	}
 	dc.registry.Get(labelValues).Inc()
 }
 
 // Add adds the given value to the counter for the given label values.
 func (dc *DynamicCounter) Add(n int64, labelValues ...string) {
+	if !dc.check(labelValues) {
+		return
+	}
 	dc.registry.Get(labelValues).Add(n)
 }
 
 // Value returns the current value for the given label values.
 func (dc *DynamicCounter) Value(labelValues ...string) int64 {
+	if !dc.check(labelValues) {
+		return 0
+	}
 	return dc.registry.Get(labelValues).Value()
 }
 
+// Delete removes the counter for the given label values so it is no longer emitted.
+// Bound handles for these label values stay valid and re-create the counter on their next update.
+func (dc *DynamicCounter) Delete(labelValues ...string) bool {
+	return dc.registry.Delete(labelValues)
+}
+
 // All returns an iterator over all StaticCounter instances in this DynamicCounter.
 // This is used by the emitter to iterate over all label combinations.
 func (dc *DynamicCounter) All() iter.Seq[*StaticCounter] {
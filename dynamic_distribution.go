@@ -17,6 +17,7 @@ type DynamicDistribution struct {
 	staticLabels map[string]string
 	labelKeys    []string
 	registry     *LabelRegistry[*StaticDistribution]
+	labelArityChecker
 }
 
 // NewDynamicDistribution creates a new DynamicDistribution with the given parameters, static labels, and dynamic label keys.
@@ -27,7 +28,7 @@ func NewDynamicDistribution(name, unit string, step, numBuckets int, staticLabel
 	if staticLabels == nil {
 		staticLabels = make(map[string]string)
 	}
-	return &DynamicDistribution{
+	dd := &DynamicDistribution{
 		Name:         name,
 		Unit:         unit,
 		Step:         step,
@@ -43,13 +44,24 @@ func NewDynamicDistribution(name, unit string, step, numBuckets int, staticLabel
 			return NewStaticDistribution(name, unit, step, numBuckets, labels)
 		}),
 	}
+	dd.labelArityChecker.init(name, labelKeys)
+	return dd
 }
 
 // Update records a value in the distribution for the given label values.
 func (dd *DynamicDistribution) Update(value int64, labelValues ...string) {
+	if !dd.check(labelValues) {
+		return
+	}
 	dd.registry.Get(labelValues).Update(value)
 }
 
+// Delete removes the distribution for the given label values so it is no longer emitted.
+// Bound handles for these label values stay valid and re-create the distribution on their next update.
+func (dd *DynamicDistribution) Delete(labelValues ...string) bool {
+	return dd.registry.Delete(labelValues)
+}
+
 // All returns an iterator over all StaticDistribution instances in this DynamicDistribution.
 // This is used by the emitter to iterate over all label combinations.
 func (dd *DynamicDistribution) All() iter.Seq[*StaticDistribution] {
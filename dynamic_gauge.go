@@ -14,6 +14,7 @@ type DynamicGauge struct {
 	staticLabels map[string]string
 	labelKeys    []string
 	registry     *LabelRegistry[*StaticGauge]
+	labelArityChecker
 }
 
 // NewDynamicGauge creates a new DynamicGauge with the given name, static labels, and dynamic label keys.
@@ -24,7 +25,7 @@ func NewDynamicGauge(name string, staticLabels map[string]string, labelKeys ...s
 	if staticLabels == nil {
 		staticLabels = make(map[string]string)
 	}
-	return &DynamicGauge{
+	dg := &DynamicGauge{
 		Name:         name,
 		staticLabels: staticLabels,
 		labelKeys:    labelKeys,
@@ -37,18 +38,32 @@ func NewDynamicGauge(name string, staticLabels map[string]string, labelKeys ...s
 			return NewStaticGauge(name, labels)
 		}),
 	}
+	dg.labelArityChecker.init(name, labelKeys)
+	return dg
 }
 
 // Set sets the gauge value for the given label values.
 func (dg *DynamicGauge) Set(n int64, labelValues ...string) {
+	if !dg.check(labelValues) {
+		return
+	}
 	dg.registry.Get(labelValues).Set(n)
 }
 
 // Value returns the current value for the given label values.
 func (dg *DynamicGauge) Value(labelValues ...string) int64 {
+	if !dg.check(labelValues) {
+		return 0
+	}
 	return dg.registry.Get(labelValues).Value()
 }
 
+// Delete removes the gauge for the given label values so it is no longer emitted.
+// Bound handles for these label values stay valid and re-create the gauge on their next update.
+func (dg *DynamicGauge) Delete(labelValues ...string) bool {
+	return dg.registry.Delete(labelValues)
+}
+
 // All returns an iterator over all StaticGauge instances in this DynamicGauge.
 // This is used by the emitter to iterate over all label combinations.
 func (dg *DynamicGauge) All() iter.Seq[*StaticGauge] {
@@ -1,6 +1,7 @@
 package gcpmetrics
 
 import (
+	"errors"
 	"sync"
 	"testing"
 )
@@ -262,3 +263,106 @@ func TestMetrics_WithStaticLabels(t *testing.T) {
 		t.Errorf("expected 1 static distribution, got %d", len(metrics.Distributions))
 	}
 }
+
+func TestMetrics_LabelArityPermissive(t *testing.T) {
+	metrics := NewMetrics()
+	counter := metrics.Counter("requests", nil, "status", "method")
+
+	counter.Inc("200")
+	counter.Inc("200", "GET")
+
+	if v := counter.(*DynamicCounter).Value("200", "GET"); v != 1 {
+		t.Errorf("expected 1, got %d", v)
+	}
+	if v := metrics.LabelArityViolations.Value("requests"); v != 1 {
+		t.Errorf("expected 1 violation, got %d", v)
+	}
+}
+
+func TestMetrics_LabelArityStrictError(t *testing.T) {
+	metrics := NewMetrics()
+	metrics.LabelArityMode = LabelArityStrictError
+	var errs []error
+	metrics.LabelArityErrorHandler = func(err error) {
+		errs = append(errs, err)
+	}
+	counter := metrics.Counter("requests", nil, "status", "method")
+
+	counter.Inc("200")
+	counter.Add(2, "200", "GET", "extra")
+	counter.Inc("200", "GET")
+
+	if len(errs) != 2 {
+		t.Fatalf("expected 2 errors, got %d", len(errs))
+	}
+	var arityErr *LabelArityError
+	if !errors.As(errs[0], &arityErr) || arityErr.Metric != "requests" || len(arityErr.LabelValues) != 1 {
+		t.Errorf("unexpected error: %v", errs[0])
+	}
+	count := 0
+	for range counter.(*DynamicCounter).All() {
+		count++
+	}
+	if count != 1 {
+		t.Errorf("expected only the valid series to be created, got %d", count)
+	}
+	if v := metrics.LabelArityViolations.Value("requests"); v != 2 {
+		t.Errorf("expected 2 violations, got %d", v)
+	}
+}
+
+func TestDynamicGauge_LabelArityStrictPanic(t *testing.T) {
+	gauge := NewDynamicGauge("temperature", nil, "location")
+	gauge.SetLabelArityMode(LabelArityStrictPanic)
+
+	defer func() {
+		if _, ok := recover().(*LabelArityError); !ok {
+			t.Error("expected panic with *LabelArityError")
+		}
+	}()
+	gauge.Set(25)
+}
+
+func TestDynamicCounter_With(t *testing.T) {
+	counter := NewDynamicCounter("requests", nil, "status", "method")
+	bound := counter.With("200", "GET")
+
+	bound.Inc()
+	bound.Add(4)
+	counter.Inc("200", "GET")
+
+	if v := counter.Value("200", "GET"); v != 6 {
+		t.Errorf("expected 6, got %d", v)
+	}
+
+	// The handle survives deletion of its series
+	if !counter.Delete("200", "GET") {
+		t.Fatal("expected series to be deleted")
+	}
+	bound.Inc()
+	if v := counter.Value("200", "GET"); v != 1 {
+		t.Errorf("expected 1 after deletion, got %d", v)
+	}
+	if v := bound.Value(); v != 1 {
+		t.Errorf("expected bound value 1, got %d", v)
+	}
+}
+
+func TestDynamicDistribution_WithStrictError(t *testing.T) {
+	metrics := NewMetrics()
+	metrics.LabelArityMode = LabelArityStrictError
+	metrics.LabelArityErrorHandler = func(err error) {}
+	dist := metrics.Distribution("latency", "ms", 100, 10, nil, "endpoint").(*DynamicDistribution)
+
+	// A mismatched handle is a no-op
+	dist.With().Update(50)
+	dist.With("/api/users").Update(50)
+
+	count := 0
+	for range dist.All() {
+		count++
+	}
+	if count != 1 {
+		t.Errorf("expected 1 distribution, got %d", count)
+	}
+}
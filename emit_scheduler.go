@@ -0,0 +1,193 @@
+package gcpmetrics
+
+import (
+	"context"
+	"math/rand/v2"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/nikolaybotev/go-gcp-metrics/clock"
+)
+
+// missedEmitsMetric is the name of the self-metric counting ticks skipped because an emission overran its interval.
+const missedEmitsMetric = "missed_emits"
+
+// ScheduleOptions contains optional configuration for scheduled emissions.
+type ScheduleOptions struct {
+	// Align aligns emissions to wall-clock multiples of the interval, e.g. :00, :10, :20 for a
+	// 10s interval. Alignment is relative to the zero time, which matches UTC boundaries for
+	// intervals that evenly divide a day.
+	Align bool
+	// MaxJitter bounds a random offset, chosen once per scheduler, that delays every emission.
+	// It spreads emissions from instances started together. It is capped at the interval.
+	MaxJitter time.Duration
+	// Clock is the source of time for the schedule. Defaults to clock.System.
+	// The per-emit deadline always uses real time, since it is enforced through a context.
+	Clock clock.Clock
+}
+
+// EmitScheduler periodically emits metrics in a background goroutine.
+// It is returned by ScheduleMetricsEmit and GcpMetrics.EmitEvery.
+//
+// Emissions never overlap: each scheduled emission is bounded by a deadline of one interval,
+// and ticks that elapse while an emission is still running are skipped rather than queued.
+// Skipped ticks are counted in MissedTicks and in the Metrics.MissedEmits self-metric.
+type EmitScheduler struct {
+	metrics     *Metrics
+	emitter     MetricsEmitter
+	interval    time.Duration
+	clock       clock.Clock
+	align       bool
+	jitter      time.Duration // Offset added to every tick
+	stop        chan struct{} // Closed to stop the emit loop
+	done        chan struct{} // Closed when the emit loop has returned
+	stopOnce    sync.Once
+	emitMu      sync.Mutex // Serializes emissions
+	flushed     bool       // Guarded by emitMu
+	missedTicks atomic.Int64
+}
+
+// newEmitScheduler creates an EmitScheduler and starts its emit loop.
+func newEmitScheduler(
+	ctx context.Context,
+	metrics *Metrics,
+	interval time.Duration,
+	emitter MetricsEmitter,
+	opts *ScheduleOptions,
+) *EmitScheduler {
+	if interval <= 0 {
+		panic("non-positive interval for ScheduleMetricsEmit")
+	}
+	if opts == nil {
+		opts = &ScheduleOptions{}
+	}
+	s := &EmitScheduler{
+		metrics:  metrics,
+		emitter:  emitter,
+		interval: interval,
+		clock:    clock.OrSystem(opts.Clock),
+		align:    opts.Align,
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+	if maxJitter := min(opts.MaxJitter, interval); maxJitter > 0 {
+		s.jitter = rand.N(maxJitter)
+	}
+	go s.run(ctx)
+	return s
+}
+
+// firstTick returns the time of the first emission for a scheduler started at now.
+func (s *EmitScheduler) firstTick(now time.Time) time.Time {
+	if !s.align {
+		return now.Add(s.interval + s.jitter)
+	}
+	tick := now.Truncate(s.interval).Add(s.jitter)
+	if !tick.After(now) {
+		tick = tick.Add(s.interval)
+	}
+	return tick
+}
+
+// run emits metrics on every tick until ctx is cancelled or the scheduler is stopped.
+func (s *EmitScheduler) run(ctx context.Context) {
+	defer close(s.done)
+	now := s.clock.Now()
+	next := s.firstTick(now)
+	timer := s.clock.NewTimer(next.Sub(now))
+	defer timer.Stop()
+	for {
+		select {
+		case <-timer.C():
+			// Prefer stopping over a tick that became ready at the same time
+			select {
+			case <-s.stop:
+				return
+			default:
+			}
+			s.emitWithDeadline(ctx)
+
+			// Skip the ticks that elapsed while emitting, tolerating up to half an interval of lateness
+			next = next.Add(s.interval)
+			now := s.clock.Now()
+			if late := now.Sub(next); late >= s.interval/2 {
+				missed := int64((late + s.interval/2) / s.interval)
+				next = next.Add(time.Duration(missed) * s.interval)
+				s.missedTicks.Add(missed)
+				if s.metrics.MissedEmits != nil {
+					s.metrics.MissedEmits.Add(missed)
+				}
+			}
+			timer.Reset(next.Sub(now))
+		case <-s.stop:
+			return
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// emitWithDeadline emits the metrics once, bounding the emission to one interval.
+func (s *EmitScheduler) emitWithDeadline(ctx context.Context) {
+	ctx, cancel := context.WithTimeout(ctx, s.interval)
+	defer cancel()
+
+	s.emitMu.Lock()
+	defer s.emitMu.Unlock()
+	s.emit(ctx)
+}
+
+// emit notifies the before-emit listeners and emits the metrics once. Must be called with emitMu held.
+func (s *EmitScheduler) emit(ctx context.Context) {
+	// Notify before emit listeners
+	s.metrics.notifyBeforeEmitListeners()
+	// Collect and emit metrics
+	s.emitter.Emit(ctx, s.metrics.Collect())
+}
+
+// Stop stops future emissions without a final flush.
+// An emission that is already in progress is allowed to complete.
+func (s *EmitScheduler) Stop() {
+	s.stopOnce.Do(func() {
+		close(s.stop)
+	})
+}
+
+// Shutdown stops future emissions, waits for any in-flight emission to complete,
+// then runs the before-emit listeners and emits the metrics one final time, so that
+// values recorded since the last tick are not lost.
+// If ctx expires before the in-flight emission completes, Shutdown returns ctx.Err()
+// without a final flush. The final flush uses ctx, so its deadline bounds the emission.
+// Only the first call to Shutdown or Close performs the final flush.
+func (s *EmitScheduler) Shutdown(ctx context.Context) error {
+	s.Stop()
+
+	select {
+	case <-s.done:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	s.emitMu.Lock()
+	defer s.emitMu.Unlock()
+	if s.flushed {
+		return nil
+	}
+	s.flushed = true
+
+	s.emit(ctx)
+	return ctx.Err()
+}
+
+// Close shuts the scheduler down with a final flush, allowing up to one emission interval for it to complete.
+func (s *EmitScheduler) Close() error {
+	ctx, cancel := context.WithTimeout(context.Background(), s.interval)
+	defer cancel()
+	return s.Shutdown(ctx)
+}
+
+// MissedTicks returns the number of ticks skipped because an emission was still running.
+func (s *EmitScheduler) MissedTicks() int64 {
+	return s.missedTicks.Load()
+}
@@ -0,0 +1,203 @@
+package gcpmetrics
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/nikolaybotev/go-gcp-metrics/clock/clocktest"
+)
+
+// recordingEmitter counts emissions and records the last counter value it saw.
+type recordingEmitter struct {
+	emits     atomic.Int64
+	lastValue atomic.Int64
+}
+
+func (e *recordingEmitter) Emit(ctx context.Context, snapshot *Snapshot) {
+	for _, point := range snapshot.Points {
+		if point.Name == "requests" {
+			e.lastValue.Store(point.Int64Value)
+		}
+	}
+	e.emits.Add(1)
+}
+
+func TestEmitScheduler_ShutdownFlushes(t *testing.T) {
+	metrics := NewMetrics()
+	counter := metrics.Counter("requests", nil)
+	listenerCalls := 0
+	metrics.AddBeforeEmitListener(func() {
+		listenerCalls++
+	})
+	emitter := &recordingEmitter{}
+
+	scheduler := ScheduleMetricsEmit(context.Background(), metrics, time.Hour, emitter, nil)
+	counter.Add(7)
+
+	if err := scheduler.Shutdown(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if n := emitter.emits.Load(); n != 1 {
+		t.Errorf("expected 1 final emit, got %d", n)
+	}
+	if v := emitter.lastValue.Load(); v != 7 {
+		t.Errorf("expected final emit to see 7, got %d", v)
+	}
+	if listenerCalls != 1 {
+		t.Errorf("expected before-emit listeners to run once, got %d", listenerCalls)
+	}
+
+	// Further calls do not flush again
+	if err := scheduler.Close(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if n := emitter.emits.Load(); n != 1 {
+		t.Errorf("expected no additional emits, got %d", n)
+	}
+}
+
+// blockingEmitter reports every emission and blocks it until release is closed.
+type blockingEmitter struct {
+	emits   atomic.Int64
+	started chan struct{}
+	release chan struct{}
+}
+
+func (e *blockingEmitter) Emit(ctx context.Context, snapshot *Snapshot) {
+	e.started <- struct{}{}
+	<-e.release
+	e.emits.Add(1)
+}
+
+func TestEmitScheduler_ShutdownWaitsForInFlightEmit(t *testing.T) {
+	fakeClock := clocktest.NewFakeClock(time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC))
+	metrics := NewMetrics()
+	emitter := &blockingEmitter{started: make(chan struct{}, 2), release: make(chan struct{})}
+
+	scheduler := ScheduleMetricsEmit(context.Background(), metrics, 10*time.Second, emitter, &ScheduleOptions{
+		Clock: fakeClock,
+	})
+	fakeClock.WaitForTimers(1)
+	fakeClock.Advance(10 * time.Second)
+	<-emitter.started
+
+	// The context ends before the in-flight emit finishes
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := scheduler.Shutdown(ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context canceled, got %v", err)
+	}
+
+	close(emitter.release)
+	if err := scheduler.Shutdown(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if n := emitter.emits.Load(); n != 2 {
+		t.Errorf("expected the in-flight emit and a final flush, got %d", n)
+	}
+}
+
+func TestEmitScheduler_CountsMissedTicks(t *testing.T) {
+	fakeClock := clocktest.NewFakeClock(time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC))
+	metrics := NewMetrics()
+	emitter := &clockEmitter{clock: fakeClock, duration: 35 * time.Second, emitted: make(chan time.Time, 2)}
+
+	scheduler := ScheduleMetricsEmit(context.Background(), metrics, 10*time.Second, emitter, &ScheduleOptions{
+		Clock: fakeClock,
+	})
+	fakeClock.WaitForTimers(1)
+	fakeClock.Advance(10 * time.Second)
+	<-emitter.emitted
+
+	// The emit ran from :10 to :45, overlapping the ticks at :20, :30 and :40
+	fakeClock.WaitForTimers(1)
+	scheduler.Stop()
+	if err := scheduler.Shutdown(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if missed := scheduler.MissedTicks(); missed != 3 {
+		t.Errorf("expected 3 missed ticks, got %d", missed)
+	}
+	if v := metrics.MissedEmits.Value(); v != 3 {
+		t.Errorf("expected self-metric to count 3 missed ticks, got %d", v)
+	}
+}
+
+func TestEmitScheduler_FirstTick(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 3, 0, time.UTC)
+	tests := []struct {
+		name     string
+		align    bool
+		jitter   time.Duration
+		expected time.Time
+	}{
+		{"unaligned", false, 0, now.Add(10 * time.Second)},
+		{"unaligned with jitter", false, 2 * time.Second, now.Add(12 * time.Second)},
+		{"aligned", true, 0, time.Date(2024, 1, 1, 12, 0, 10, 0, time.UTC)},
+		{"aligned with jitter after now", true, 5 * time.Second, time.Date(2024, 1, 1, 12, 0, 5, 0, time.UTC)},
+		{"aligned with jitter before now", true, 2 * time.Second, time.Date(2024, 1, 1, 12, 0, 12, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &EmitScheduler{interval: 10 * time.Second, align: tt.align, jitter: tt.jitter}
+			if tick := s.firstTick(now); !tick.Equal(tt.expected) {
+				t.Errorf("expected %v, got %v", tt.expected, tick)
+			}
+		})
+	}
+}
+
+// clockEmitter reports every emission and advances a fake clock to simulate its duration.
+type clockEmitter struct {
+	clock    *clocktest.FakeClock
+	duration time.Duration
+	emitted  chan time.Time
+}
+
+func (e *clockEmitter) Emit(ctx context.Context, snapshot *Snapshot) {
+	now := e.clock.Now()
+	e.clock.Advance(e.duration)
+	e.emitted <- now
+}
+
+func TestEmitScheduler_FakeClock(t *testing.T) {
+	start := time.Date(2024, 1, 1, 12, 0, 3, 0, time.UTC)
+	fakeClock := clocktest.NewFakeClock(start)
+	metrics := NewMetrics()
+	emitter := &clockEmitter{clock: fakeClock, emitted: make(chan time.Time, 10)}
+
+	scheduler := ScheduleMetricsEmit(context.Background(), metrics, 10*time.Second, emitter, &ScheduleOptions{
+		Align: true,
+		Clock: fakeClock,
+	})
+	defer scheduler.Stop()
+
+	// The first emission happens on the next 10s boundary
+	fakeClock.WaitForTimers(1)
+	fakeClock.Advance(7 * time.Second)
+	if at := <-emitter.emitted; !at.Equal(start.Add(7 * time.Second)) {
+		t.Errorf("expected first emit at :10, got %v", at)
+	}
+
+	// An emission overrunning two intervals skips the ticks it overlapped
+	emitter.duration = 25 * time.Second
+	fakeClock.WaitForTimers(1)
+	fakeClock.Advance(10 * time.Second)
+	if at := <-emitter.emitted; !at.Equal(start.Add(17 * time.Second)) {
+		t.Errorf("expected second emit at :20, got %v", at)
+	}
+
+	emitter.duration = 0
+	fakeClock.WaitForTimers(1)
+	if n := scheduler.MissedTicks(); n != 2 {
+		t.Errorf("expected 2 missed ticks, got %d", n)
+	}
+	fakeClock.Advance(5 * time.Second)
+	if at := <-emitter.emitted; !at.Equal(start.Add(47 * time.Second)) {
+		t.Errorf("expected third emit at :50, got %v", at)
+	}
+}
@@ -5,12 +5,13 @@ import (
 	"log"
 	"math/rand"
 	"os"
+	"os/signal"
+	"syscall"
 	"time"
 
 	monitoring "cloud.google.com/go/monitoring/apiv3/v2"
 	gcpmetrics "github.com/nikolaybotev/go-gcp-metrics"
 	"github.com/nikolaybotev/go-gcp-metrics/cloud_metadata"
-	"google.golang.org/genproto/googleapis/api/monitoredres"
 )
 
 func main() {
@@ -36,21 +37,19 @@ func main() {
 	}
 	defer client.Close()
 
-	// Create the MonitoredResource instance
-	resource := &monitoredres.MonitoredResource{
-		Type: "generic_node",
-		Labels: map[string]string{
-			"project_id": projectID,
-			"location":   "us-central1",
-			"namespace":  "",
-			"node_id":    instance,
-		},
-	}
+	// Detect the MonitoredResource of the runtime, falling back to generic_node
+	resource := cloud_metadata.DetectMonitoredResource(projectID, nil)
+	infoLogger.Printf("Using monitored resource: %s %v", resource.Type, resource.Labels)
 
 	// Create GcpMetrics and add counters, distributions, and gauges
 	metrics := gcpmetrics.NewGcpMetrics(client, projectID, resource, "go/", &gcpmetrics.Options{
 		ErrorLogger: errorLogger,
 		InfoLogger:  infoLogger,
+		// Emit on 10s wall-clock boundaries, spread by up to a second across instances
+		EmitSchedule: &gcpmetrics.ScheduleOptions{
+			Align:     true,
+			MaxJitter: time.Second,
+		},
 	})
 
 	// Static metrics (no dynamic label keys - labels fixed at creation time)
@@ -80,13 +79,16 @@ func main() {
 		infoLogger.Println("Updated gauges")
 	})
 
+	// Stop on SIGINT or SIGTERM
+	runCtx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
 	// Emit counters every 10 seconds
-	ticker := metrics.EmitEvery(ctx, 10*time.Second)
-	defer ticker.Stop()
+	scheduler := metrics.EmitEvery(runCtx, 10*time.Second)
 
 	// Simulate some work and increment counters and gauge
 	infoLogger.Println("Starting metrics emission...")
-	for {
+	for runCtx.Err() == nil {
 		// Update static counters (no label values needed)
 		counterA.Add(rand.Int63n(100))
 		counterB.Add(rand.Int63n(50))
@@ -124,4 +126,12 @@ func main() {
 
 		time.Sleep(1 * time.Second)
 	}
+
+	// Flush the values recorded since the last emission before exiting
+	infoLogger.Println("Shutting down...")
+	shutdownCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	if err := scheduler.Shutdown(shutdownCtx); err != nil {
+		errorLogger.Printf("failed to flush metrics on shutdown: %v", err)
+	}
 }
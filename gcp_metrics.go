@@ -6,6 +6,7 @@ import (
 	"time"
 
 	monitoring "cloud.google.com/go/monitoring/apiv3/v2"
+	"github.com/nikolaybotev/go-gcp-metrics/clock"
 	"google.golang.org/genproto/googleapis/api/monitoredres"
 )
 
@@ -14,6 +15,14 @@ type Options struct {
 	ErrorLogger  *log.Logger
 	InfoLogger   *log.Logger
 	CommonLabels map[string]string
+	// LabelArityMode controls how dynamic metrics handle a label value count that
+	// does not match their label keys. Violations are reported to ErrorLogger.
+	LabelArityMode LabelArityMode
+	// EmitSchedule configures the alignment and jitter of emissions scheduled by EmitEvery.
+	EmitSchedule *ScheduleOptions
+	// Clock is the source of time for collected snapshots and, unless EmitSchedule sets its own,
+	// for the emission schedule. Defaults to clock.System.
+	Clock clock.Clock
 }
 
 // GcpMetrics is a Metrics implementation that emits metrics to Google Cloud Monitoring.
@@ -21,6 +30,7 @@ type Options struct {
 type GcpMetrics struct {
 	*Metrics
 	*GcpMetricsEmitter
+	emitSchedule *ScheduleOptions
 }
 
 // NewGcpMetrics creates a new GcpMetrics instance.
@@ -31,18 +41,45 @@ func NewGcpMetrics(
 	metricsNamePrefix string,
 	opts *Options,
 ) *GcpMetrics {
+	if opts == nil {
+		opts = &Options{}
+	}
+	emitter := NewGcpMetricsEmitter(client, projectID, monitoredResource, metricsNamePrefix, opts)
+	metrics := NewMetrics()
+	metrics.Clock = opts.Clock
+	metrics.LabelArityMode = opts.LabelArityMode
+	metrics.LabelArityErrorHandler = func(err error) {
+		emitter.errorLogger.Println(err)
+	}
+	emitSchedule := &ScheduleOptions{}
+	if opts.EmitSchedule != nil {
+		*emitSchedule = *opts.EmitSchedule
+	}
+	if emitSchedule.Clock == nil {
+		emitSchedule.Clock = opts.Clock
+	}
 	return &GcpMetrics{
-		Metrics:           NewMetrics(),
-		GcpMetricsEmitter: NewGcpMetricsEmitter(client, projectID, monitoredResource, metricsNamePrefix, opts),
+		Metrics:           metrics,
+		GcpMetricsEmitter: emitter,
+		emitSchedule:      emitSchedule,
 	}
 }
 
-// Emit delegates to the emitter's Emit method, passing the embedded Metrics.
+// Emit collects a snapshot of the embedded Metrics and emits it.
 func (me *GcpMetrics) Emit(ctx context.Context) {
-	me.GcpMetricsEmitter.Emit(ctx, me.Metrics)
+	me.GcpMetricsEmitter.Emit(ctx, me.Metrics.Collect())
 }
 
-// EmitEvery delegates to the emitter's EmitEvery method, passing the embedded Metrics.
-func (me *GcpMetrics) EmitEvery(ctx context.Context, interval time.Duration) *time.Ticker {
-	return ScheduleMetricsEmit(ctx, me.Metrics, interval, me.GcpMetricsEmitter)
+// EmitEvery schedules the embedded Metrics to be emitted at the given interval.
+// Additional emitters, such as a PrometheusExporter, receive every snapshot alongside the
+// GcpMetricsEmitter through a MultiEmitter.
+// Call Shutdown or Close on the returned EmitScheduler to flush the final values on exit.
+func (me *GcpMetrics) EmitEvery(ctx context.Context, interval time.Duration, emitters ...MetricsEmitter) *EmitScheduler {
+	var emitter MetricsEmitter = me.GcpMetricsEmitter
+	if len(emitters) > 0 {
+		multi := NewMultiEmitter(append([]MetricsEmitter{me.GcpMetricsEmitter}, emitters...)...)
+		multi.ErrorLogger = me.errorLogger
+		emitter = multi
+	}
+	return ScheduleMetricsEmit(ctx, me.Metrics, interval, emitter, me.emitSchedule)
 }
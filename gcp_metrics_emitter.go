@@ -8,11 +8,9 @@ import (
 	"math"
 	"path"
 	"strings"
-	"time"
 
 	monitoring "cloud.google.com/go/monitoring/apiv3/v2"
 	"cloud.google.com/go/monitoring/apiv3/v2/monitoringpb"
-	"github.com/nikolaybotev/go-gcp-metrics/iterutil"
 	"google.golang.org/genproto/googleapis/api/distribution"
 	"google.golang.org/genproto/googleapis/api/metric"
 	"google.golang.org/genproto/googleapis/api/monitoredres"
@@ -81,8 +79,9 @@ func (me *GcpMetricsEmitter) buildMetric(name string, specificLabels map[string]
 	}
 }
 
-// Emit emits metrics from the provided Metrics to Google Cloud Monitoring.
-func (me *GcpMetricsEmitter) Emit(ctx context.Context, metrics *Metrics) {
+// Emit emits the points in the snapshot to Google Cloud Monitoring.
+// Distributions without samples are skipped.
+func (me *GcpMetricsEmitter) Emit(ctx context.Context, snapshot *Snapshot) {
 	if me.Client == nil {
 		me.errorLogger.Println("Client must be set in GcpMetricsEmitter")
 		return
@@ -96,104 +95,7 @@ func (me *GcpMetricsEmitter) Emit(ctx context.Context, metrics *Metrics) {
 		return
 	}
 
-	now := time.Now()
-	interval := &monitoringpb.TimeInterval{
-		EndTime: timestamppb.New(now),
-	}
-
-	var timeSeriesList []*monitoringpb.TimeSeries
-
-	// Combine static and dynamic counters into a single iterator
-	allCounters := iterutil.CombineMetrics(metrics.Counters, metrics.DynamicCounters)
-
-	// Emit all counters (static + dynamic)
-	for staticCounter := range allCounters {
-		value := staticCounter.Value()
-
-		ts := &monitoringpb.TimeSeries{
-			Metric:   me.buildMetric(staticCounter.Name, staticCounter.Labels),
-			Resource: me.MonitoredResource,
-			Points: []*monitoringpb.Point{
-				{
-					Interval: interval,
-					Value: &monitoringpb.TypedValue{
-						Value: &monitoringpb.TypedValue_Int64Value{
-							Int64Value: value,
-						},
-					},
-				},
-			},
-		}
-
-		timeSeriesList = append(timeSeriesList, ts)
-	}
-
-	// Combine static and dynamic gauges into a single iterator
-	allGauges := iterutil.CombineMetrics(metrics.Gauges, metrics.DynamicGauges)
-
-	// Emit all gauges (static + dynamic)
-	for staticGauge := range allGauges {
-		value := staticGauge.Value()
-
-		ts := &monitoringpb.TimeSeries{
-			Metric:   me.buildMetric(staticGauge.Name, staticGauge.Labels),
-			Resource: me.MonitoredResource,
-			Points: []*monitoringpb.Point{
-				{
-					Interval: interval,
-					Value: &monitoringpb.TypedValue{
-						Value: &monitoringpb.TypedValue_Int64Value{
-							Int64Value: value,
-						},
-					},
-				},
-			},
-		}
-
-		timeSeriesList = append(timeSeriesList, ts)
-	}
-
-	// Combine static and dynamic distributions into a single iterator
-	allDistributions := iterutil.CombineMetrics(metrics.Distributions, metrics.DynamicDistributions)
-
-	// Emit all distributions (static + dynamic)
-	for staticDist := range allDistributions {
-		value := staticDist.GetAndClear()
-		if value.NumSamples == 0 {
-			continue
-		}
-
-		ts := &monitoringpb.TimeSeries{
-			Metric:   me.buildMetric(staticDist.Name, staticDist.Labels),
-			Unit:     staticDist.Unit,
-			Resource: me.MonitoredResource,
-			Points: []*monitoringpb.Point{
-				{
-					Interval: interval,
-					Value: &monitoringpb.TypedValue{
-						Value: &monitoringpb.TypedValue_DistributionValue{
-							DistributionValue: &distribution.Distribution{
-								Count:                 value.NumSamples,
-								Mean:                  value.Mean,
-								SumOfSquaredDeviation: value.SumOfSquaredDeviation,
-								BucketOptions: &distribution.Distribution_BucketOptions{
-									Options: &distribution.Distribution_BucketOptions_ExplicitBuckets{
-										ExplicitBuckets: &distribution.Distribution_BucketOptions_Explicit{
-											Bounds: staticDist.BucketBounds(),
-										},
-									},
-								},
-								BucketCounts: value.Buckets,
-							},
-						},
-					},
-				},
-			},
-		}
-
-		timeSeriesList = append(timeSeriesList, ts)
-	}
-
+	timeSeriesList := me.buildTimeSeries(snapshot)
 	if len(timeSeriesList) == 0 {
 		return
 	}
@@ -248,3 +150,70 @@ func (me *GcpMetricsEmitter) Emit(ctx context.Context, metrics *Metrics) {
 		}
 	}
 }
+
+// buildTimeSeries converts the points in the snapshot into time series.
+// Distributions without samples are skipped.
+func (me *GcpMetricsEmitter) buildTimeSeries(snapshot *Snapshot) []*monitoringpb.TimeSeries {
+	var timeSeriesList []*monitoringpb.TimeSeries
+
+	for _, point := range snapshot.Points {
+		// Points are written with an end time only, so Cloud Monitoring records every metric as a GAUGE
+		interval := &monitoringpb.TimeInterval{
+			EndTime: timestamppb.New(point.EndTime),
+		}
+
+		var value *monitoringpb.TypedValue
+		var valueType metric.MetricDescriptor_ValueType
+		switch point.ValueType {
+		case ValueTypeInt64:
+			valueType = metric.MetricDescriptor_INT64
+			value = &monitoringpb.TypedValue{
+				Value: &monitoringpb.TypedValue_Int64Value{
+					Int64Value: point.Int64Value,
+				},
+			}
+		case ValueTypeDistribution:
+			dist := point.Distribution
+			if dist.NumSamples == 0 {
+				continue
+			}
+			valueType = metric.MetricDescriptor_DISTRIBUTION
+			value = &monitoringpb.TypedValue{
+				Value: &monitoringpb.TypedValue_DistributionValue{
+					DistributionValue: &distribution.Distribution{
+						Count:                 dist.NumSamples,
+						Mean:                  dist.Mean,
+						SumOfSquaredDeviation: dist.SumOfSquaredDeviation,
+						BucketOptions: &distribution.Distribution_BucketOptions{
+							Options: &distribution.Distribution_BucketOptions_ExplicitBuckets{
+								ExplicitBuckets: &distribution.Distribution_BucketOptions_Explicit{
+									Bounds: point.BucketOptions.Bounds,
+								},
+							},
+						},
+						BucketCounts: dist.Buckets,
+					},
+				},
+			}
+		default:
+			continue
+		}
+
+		ts := &monitoringpb.TimeSeries{
+			Metric:     me.buildMetric(point.Name, point.Labels),
+			MetricKind: metric.MetricDescriptor_GAUGE,
+			ValueType:  valueType,
+			Unit:       point.Unit,
+			Resource:   me.MonitoredResource,
+			Points: []*monitoringpb.Point{
+				{
+					Interval: interval,
+					Value:    value,
+				},
+			},
+		}
+
+		timeSeriesList = append(timeSeriesList, ts)
+	}
+	return timeSeriesList
+}
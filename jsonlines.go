@@ -0,0 +1,168 @@
+package gcpmetrics
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log"
+	"maps"
+	"path"
+	"sync"
+	"time"
+
+	"google.golang.org/genproto/googleapis/api/monitoredres"
+)
+
+// JSONLinesOptions contains optional configuration for JSONLinesEmitter.
+type JSONLinesOptions struct {
+	// MetricsNamePrefix is inserted between custom.googleapis.com/ and the metric name, as in GcpMetricsEmitter.
+	MetricsNamePrefix string
+	// CommonLabels are merged into the labels of every time series.
+	CommonLabels map[string]string
+	// Resource is written with every time series. It may be nil.
+	Resource *monitoredres.MonitoredResource
+	// Severity is the Cloud Logging severity of every line. Defaults to INFO.
+	Severity    string
+	ErrorLogger *log.Logger
+}
+
+// JSONLinesEmitter is a MetricsEmitter that writes one JSON object per time series per emit.
+//
+// The objects mirror the time series GcpMetricsEmitter would send to Cloud Monitoring, and carry
+// the severity, message and time fields of Cloud Logging structured logs, so that writing them to
+// stdout on Cloud Run or GKE produces log entries suitable for log-based metrics. Like GcpMetricsEmitter,
+// every time series is a GAUGE whose interval has an end time only. Distributions without samples are skipped.
+type JSONLinesEmitter struct {
+	metricsNamePrefix string
+	commonLabels      map[string]string
+	resource          *jsonResource
+	severity          string
+	errorLogger       *log.Logger
+	mu                sync.Mutex
+	encoder           *json.Encoder // Guarded by mu
+}
+
+// jsonTimeSeries is one line written by JSONLinesEmitter.
+type jsonTimeSeries struct {
+	Severity   string        `json:"severity"`
+	Message    string        `json:"message"`
+	Time       time.Time     `json:"time"`
+	Metric     jsonMetric    `json:"metric"`
+	Resource   *jsonResource `json:"resource,omitempty"`
+	MetricKind string        `json:"metricKind"`
+	ValueType  string        `json:"valueType"`
+	Unit       string        `json:"unit,omitempty"`
+	Interval   jsonInterval  `json:"interval"`
+	Value      jsonValue     `json:"value"`
+}
+
+type jsonMetric struct {
+	Type   string            `json:"type"`
+	Labels map[string]string `json:"labels,omitempty"`
+}
+
+type jsonResource struct {
+	Type   string            `json:"type"`
+	Labels map[string]string `json:"labels,omitempty"`
+}
+
+type jsonInterval struct {
+	EndTime time.Time `json:"endTime"`
+}
+
+type jsonValue struct {
+	Int64Value        *int64            `json:"int64Value,omitempty"`
+	DistributionValue *jsonDistribution `json:"distributionValue,omitempty"`
+}
+
+type jsonDistribution struct {
+	Count                 int64     `json:"count"`
+	Mean                  float64   `json:"mean"`
+	SumOfSquaredDeviation float64   `json:"sumOfSquaredDeviation"`
+	Bounds                []float64 `json:"bounds"`
+	BucketCounts          []int64   `json:"bucketCounts"`
+}
+
+// NewJSONLinesEmitter creates a new JSONLinesEmitter that writes to w. opts may be nil.
+func NewJSONLinesEmitter(w io.Writer, opts *JSONLinesOptions) *JSONLinesEmitter {
+	// Set defaults if nil
+	if opts == nil {
+		opts = &JSONLinesOptions{}
+	}
+	je := &JSONLinesEmitter{
+		metricsNamePrefix: opts.MetricsNamePrefix,
+		commonLabels:      opts.CommonLabels,
+		severity:          opts.Severity,
+		errorLogger:       opts.ErrorLogger,
+		encoder:           json.NewEncoder(w),
+	}
+	if je.severity == "" {
+		je.severity = "INFO"
+	}
+	if je.errorLogger == nil {
+		je.errorLogger = log.Default()
+	}
+	if opts.Resource != nil {
+		je.resource = &jsonResource{Type: opts.Resource.Type, Labels: opts.Resource.Labels}
+	}
+	je.encoder.SetEscapeHTML(false)
+	return je
+}
+
+// Emit writes the points in the snapshot, one JSON object per line.
+func (je *JSONLinesEmitter) Emit(ctx context.Context, snapshot *Snapshot) {
+	je.mu.Lock()
+	defer je.mu.Unlock()
+
+	for _, point := range snapshot.Points {
+		ts := jsonTimeSeries{
+			Severity: je.severity,
+			Time:     point.EndTime,
+			Metric: jsonMetric{
+				Type:   "custom.googleapis.com/" + path.Join(je.metricsNamePrefix, point.Name),
+				Labels: je.mergeLabels(point.Labels),
+			},
+			Resource:   je.resource,
+			MetricKind: MetricKindGauge.String(),
+			ValueType:  point.ValueType.String(),
+			Unit:       point.Unit,
+			Interval:   jsonInterval{EndTime: point.EndTime},
+		}
+		ts.Message = "metric " + ts.Metric.Type
+
+		switch point.ValueType {
+		case ValueTypeInt64:
+			value := point.Int64Value
+			ts.Value.Int64Value = &value
+		case ValueTypeDistribution:
+			dist := point.Distribution
+			if dist.NumSamples == 0 {
+				continue
+			}
+			ts.Value.DistributionValue = &jsonDistribution{
+				Count:                 dist.NumSamples,
+				Mean:                  dist.Mean,
+				SumOfSquaredDeviation: dist.SumOfSquaredDeviation,
+				Bounds:                point.BucketOptions.Bounds,
+				BucketCounts:          dist.Buckets,
+			}
+		default:
+			continue
+		}
+
+		if err := je.encoder.Encode(ts); err != nil {
+			je.errorLogger.Printf("failed to write metric %s: %v", ts.Metric.Type, err)
+			return
+		}
+	}
+}
+
+// mergeLabels merges common labels with metric-specific labels.
+func (je *JSONLinesEmitter) mergeLabels(specific map[string]string) map[string]string {
+	if len(je.commonLabels) == 0 {
+		return specific
+	}
+	labels := maps.Clone(je.commonLabels)
+	maps.Copy(labels, specific)
+	return labels
+}
@@ -0,0 +1,94 @@
+package gcpmetrics
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"maps"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nikolaybotev/go-gcp-metrics/clock/clocktest"
+	"google.golang.org/genproto/googleapis/api/monitoredres"
+)
+
+func TestJSONLinesEmitter(t *testing.T) {
+	clk := clocktest.NewFakeClock(time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC))
+	metrics := NewMetrics()
+	metrics.Clock = clk
+	metrics.Counter("requests", nil, "status").Add(3, "200")
+	latency := metrics.Distribution("latency", "ms", 100, 2, nil)
+	latency.Update(10)
+	metrics.Distribution("idle", "ms", 100, 2, nil)
+	clk.Advance(time.Minute)
+
+	var buf bytes.Buffer
+	emitter := NewJSONLinesEmitter(&buf, &JSONLinesOptions{
+		MetricsNamePrefix: "app",
+		CommonLabels:      map[string]string{"env": "test"},
+		Resource:          &monitoredres.MonitoredResource{Type: "global", Labels: map[string]string{"project_id": "p"}},
+	})
+	emitter.Emit(context.Background(), metrics.Collect())
+
+	expected := `{"severity":"INFO","message":"metric custom.googleapis.com/app/requests","time":"2024-01-02T03:05:05Z",` +
+		`"metric":{"type":"custom.googleapis.com/app/requests","labels":{"env":"test","status":"200"}},` +
+		`"resource":{"type":"global","labels":{"project_id":"p"}},"metricKind":"GAUGE","valueType":"INT64",` +
+		`"interval":{"endTime":"2024-01-02T03:05:05Z"},"value":{"int64Value":3}}
+{"severity":"INFO","message":"metric custom.googleapis.com/app/latency","time":"2024-01-02T03:05:05Z",` +
+		`"metric":{"type":"custom.googleapis.com/app/latency","labels":{"env":"test"}},` +
+		`"resource":{"type":"global","labels":{"project_id":"p"}},"metricKind":"GAUGE","valueType":"DISTRIBUTION","unit":"ms",` +
+		`"interval":{"endTime":"2024-01-02T03:05:05Z"},` +
+		`"value":{"distributionValue":{"count":1,"mean":10,"sumOfSquaredDeviation":0,"bounds":[0,100,200],"bucketCounts":[0,1,0,0]}}}
+`
+	if got := buf.String(); got != expected {
+		t.Errorf("unexpected output:\n%s\nexpected:\n%s", got, expected)
+	}
+	if strings.Contains(buf.String(), "idle") {
+		t.Error("distributions without samples should be skipped")
+	}
+}
+
+func TestJSONLinesEmitter_MatchesGcpMetricsEmitter(t *testing.T) {
+	metrics := NewMetrics()
+	metrics.Counter("requests", nil, "status").Add(3, "200")
+	metrics.Gauge("temperature", nil).Set(25)
+	metrics.Distribution("latency", "ms", 100, 2, nil).Update(10)
+	snapshot := metrics.Collect()
+
+	resource := &monitoredres.MonitoredResource{Type: "global", Labels: map[string]string{"project_id": "p"}}
+	commonLabels := map[string]string{"env": "test"}
+	var buf bytes.Buffer
+	NewJSONLinesEmitter(&buf, &JSONLinesOptions{MetricsNamePrefix: "app", CommonLabels: commonLabels, Resource: resource}).
+		Emit(context.Background(), snapshot)
+	gcpEmitter := NewGcpMetricsEmitter(nil, "p", resource, "app", &Options{CommonLabels: commonLabels})
+	timeSeries := gcpEmitter.buildTimeSeries(snapshot)
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != len(timeSeries) {
+		t.Fatalf("expected %d lines, got %d", len(timeSeries), len(lines))
+	}
+	for i, ts := range timeSeries {
+		var line map[string]any
+		if err := json.Unmarshal([]byte(lines[i]), &line); err != nil {
+			t.Fatal(err)
+		}
+		var got jsonTimeSeries
+		if err := json.Unmarshal([]byte(lines[i]), &got); err != nil {
+			t.Fatal(err)
+		}
+		if got.Metric.Type != ts.Metric.Type || !maps.Equal(got.Metric.Labels, ts.Metric.Labels) {
+			t.Errorf("line %d: metric %v does not match %v", i, got.Metric, ts.Metric)
+		}
+		if got.MetricKind != ts.MetricKind.String() || got.ValueType != ts.ValueType.String() {
+			t.Errorf("line %d: %s %s does not match %s %s", i, got.MetricKind, got.ValueType, ts.MetricKind, ts.ValueType)
+		}
+		interval := ts.Points[0].Interval
+		if _, ok := line["interval"].(map[string]any)["startTime"]; ok || interval.StartTime != nil {
+			t.Errorf("line %d: expected no start time, got %v and %v", i, line["interval"], interval)
+		}
+		if !got.Interval.EndTime.Equal(interval.EndTime.AsTime()) {
+			t.Errorf("line %d: end time %v does not match %v", i, got.Interval.EndTime, interval.EndTime.AsTime())
+		}
+	}
+}
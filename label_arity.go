@@ -0,0 +1,121 @@
+package gcpmetrics
+
+import (
+	"fmt"
+	"log"
+	"sync/atomic"
+)
+
+// LabelArityMode controls how dynamic metrics react when the number of label values
+// passed to an update does not match the number of label keys defined at creation time.
+type LabelArityMode int32
+
+const (
+	// LabelArityPermissive accepts mismatched label values (the default).
+	// Missing values leave their keys unset and extra values are ignored,
+	// matching the Java library's behavior.
+	LabelArityPermissive LabelArityMode = iota
+	// LabelArityStrictError drops the update and reports a *LabelArityError
+	// to the configured error handler.
+	LabelArityStrictError
+	// LabelArityStrictPanic panics with a *LabelArityError. Intended for tests.
+	LabelArityStrictPanic
+)
+
+// labelArityViolationsMetric is the name of the self-metric counting label arity violations.
+const labelArityViolationsMetric = "label_arity_violations"
+
+// String returns the name of the mode.
+func (m LabelArityMode) String() string {
+	switch m {
+	case LabelArityPermissive:
+		return "permissive"
+	case LabelArityStrictError:
+		return "strict-error"
+	case LabelArityStrictPanic:
+		return "strict-panic"
+	default:
+		return fmt.Sprintf("LabelArityMode(%d)", int32(m))
+	}
+}
+
+// LabelArityError describes an update whose label values do not match the metric's label keys.
+type LabelArityError struct {
+	Metric      string
+	LabelKeys   []string
+	LabelValues []string
+}
+
+// Error implements the error interface.
+func (e *LabelArityError) Error() string {
+	return fmt.Sprintf("metric %s expects %d label values %v, got %d %q",
+		e.Metric, len(e.LabelKeys), e.LabelKeys, len(e.LabelValues), e.LabelValues)
+}
+
+// labelArityChecker validates label value counts for a single dynamic metric.
+// The mode may be changed at any time; violations are counted in an optional self-metric.
+type labelArityChecker struct {
+	metric       string
+	labelKeys    []string
+	mode         atomic.Int32
+	errorHandler func(err error)
+	violations   *DynamicCounter
+}
+
+// init configures the checker. It must be called before the owning metric is shared.
+func (c *labelArityChecker) init(metric string, labelKeys []string) {
+	c.metric = metric
+	c.labelKeys = labelKeys
+}
+
+// configure applies the Metrics-level arity settings to the checker.
+func (c *labelArityChecker) configure(mode LabelArityMode, errorHandler func(err error), violations *DynamicCounter) {
+	c.mode.Store(int32(mode))
+	c.errorHandler = errorHandler
+	c.violations = violations
+}
+
+// check reports whether an update with the given label values should proceed.
+func (c *labelArityChecker) check(labelValues []string) bool {
+	if len(labelValues) == len(c.labelKeys) {
+		return true
+	}
+
+	if c.violations != nil {
+		c.violations.Inc(c.metric)
+	}
+
+	switch LabelArityMode(c.mode.Load()) {
+	case LabelArityStrictError:
+		err := &LabelArityError{
+			Metric:      c.metric,
+			LabelKeys:   c.labelKeys,
+			LabelValues: append([]string(nil), labelValues...),
+		}
+		if c.errorHandler != nil {
+			c.errorHandler(err)
+		} else {
+			log.Default().Println(err)
+		}
+		return false
+	case LabelArityStrictPanic:
+		panic(&LabelArityError{
+			Metric:      c.metric,
+			LabelKeys:   c.labelKeys,
+			LabelValues: append([]string(nil), labelValues...),
+		})
+	default:
+		return true
+	}
+}
+
+// LabelArityMode returns the metric's current label arity mode.
+func (c *labelArityChecker) LabelArityMode() LabelArityMode {
+	return LabelArityMode(c.mode.Load())
+}
+
+// SetLabelArityMode overrides the label arity mode for this metric.
+// It is safe to call concurrently with updates.
+func (c *labelArityChecker) SetLabelArityMode(mode LabelArityMode) {
+	c.mode.Store(int32(mode))
+}
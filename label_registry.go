@@ -1,9 +1,11 @@
 package gcpmetrics
 
 import (
+	"hash/maphash"
 	"iter"
-	"strings"
+	"slices"
 	"sync"
+	"sync/atomic"
 )
 
 // labelValuesToMap converts label keys and values to a map[string]string.
@@ -11,6 +13,7 @@ import (
 // - If fewer values than keys: only the first N keys get values (where N = len(values))
 // - If more values than keys: only the first N values are used (where N = len(keys))
 // This matches the Java library's permissive behavior.
+// Strict arity modes are enforced earlier, by each dynamic metric's labelArityChecker.
 func labelValuesToMap(keys []string, values []string) map[string]string {
 	result := make(map[string]string)
 	for i, key := range keys {
@@ -22,53 +25,175 @@ func labelValuesToMap(keys []string, values []string) map[string]string {
 	return result
 }
 
-// labelValuesKey creates a unique key from label values for use in the registry.
-// Uses null byte as separator since it's not a valid character in label values.
-func labelValuesKey(values []string) string {
-	return strings.Join(values, "\x00")
+// labelValuesHash hashes label values for use as a registry key without allocating.
+// Each value is hashed separately and mixed in order, so the hash depends on value positions.
+// Distinct combinations may collide; the registry resolves collisions by comparing the values.
+func labelValuesHash(seed maphash.Seed, values []string) uint64 {
+	h := uint64(len(values))
+	for _, v := range values {
+		h = (h ^ maphash.String(seed, v)) * 0x100000001b3
+	}
+	return h
+}
+
+// labelTable maps label value hashes to the entries with that hash.
+// Tables published in LabelRegistry.read are immutable.
+type labelTable[T any] map[uint64][]*labelEntry[T]
+
+// lookup returns the entry for the given label values, or nil if there is none.
+func (t labelTable[T]) lookup(hash uint64, labelValues []string) *labelEntry[T] {
+	for _, entry := range t[hash] {
+		if slices.Equal(entry.labelValues, labelValues) {
+			return entry
+		}
+	}
+	return nil
 }
 
 // LabelRegistry manages a thread-safe mapping from label value combinations to metric instances.
-// It uses sync.Map for concurrent access and lazy creation of metric instances.
+// Lookups of existing label combinations are lock-free and allocation-free: label values are
+// hashed in place and looked up in an immutable table published through an atomic pointer.
+// New combinations are added to a dirty copy of the table under a mutex, which is promoted
+// to the read table once enough lookups have missed it (the same amortization as sync.Map).
 type LabelRegistry[T any] struct {
 	labelKeys []string                     // Immutable after creation - DO NOT MODIFY
-	registry  sync.Map                     // map[string]T - key is label values joined
 	factory   func(labelValues []string) T // Factory function to create new metric instances
+	seed      maphash.Seed
+	read      atomic.Pointer[labelTable[T]] // Immutable snapshot for lock-free lookups
+	mu        sync.Mutex                    // Guards dirty and misses
+	dirty     labelTable[T]                 // Superset of read when non-nil
+	misses    int                           // Lookups that missed read since the last promotion
+}
+
+// labelEntry holds a metric instance stored in a LabelRegistry.
+// The removed flag lets bound handles detect that their series was deleted and re-resolve it.
+type labelEntry[T any] struct {
+	labelValues []string
+	value       T
+	removed     atomic.Bool
 }
 
 // newLabelRegistry creates a new LabelRegistry with the given label keys and factory function.
 func newLabelRegistry[T any](labelKeys []string, factory func(labelValues []string) T) *LabelRegistry[T] {
-	return &LabelRegistry[T]{
+	lr := &LabelRegistry[T]{
 		labelKeys: labelKeys,
 		factory:   factory,
+		seed:      maphash.MakeSeed(),
 	}
+	lr.read.Store(&labelTable[T]{})
+	return lr
 }
 
 // Get retrieves or creates a metric instance for the given label values.
-// This method is thread-safe and uses atomic operations to ensure only one
-// instance is created per unique label combination, even under concurrent access.
+// This method is thread-safe and ensures only one instance is created per unique
+// label combination, even under concurrent access. It does not allocate when the
+// label combination already exists.
 func (lr *LabelRegistry[T]) Get(labelValues []string) T {
-	key := labelValuesKey(labelValues)
+	return lr.getEntry(labelValues).value
+}
+
+// getEntry retrieves or creates the registry entry for the given label values.
+// labelValues must not be retained, so that variadic callers' slices stay on the stack.
+func (lr *LabelRegistry[T]) getEntry(labelValues []string) *labelEntry[T] {
+	hash := labelValuesHash(lr.seed, labelValues)
+
+	// Fast path: lock-free lookup in the read table
+	if entry := (*lr.read.Load()).lookup(hash, labelValues); entry != nil {
+		return entry
+	}
 
-	// Try to load existing value
-	if value, ok := lr.registry.Load(key); ok {
-		return value.(T)
+	lr.mu.Lock()
+	defer lr.mu.Unlock()
+
+	// Re-check the read table, which may have been promoted while waiting for the lock
+	read := *lr.read.Load()
+	if entry := read.lookup(hash, labelValues); entry != nil {
+		return entry
+	}
+	lr.ensureDirty(read)
+
+	entry := lr.dirty.lookup(hash, labelValues)
+	if entry == nil {
+		// Copy the label values: the caller's slice must not escape
+		values := slices.Clone(labelValues)
+		entry = &labelEntry[T]{labelValues: values, value: lr.factory(values)}
+		// Never mutate a bucket slice that may be shared with the read table
+		bucket := lr.dirty[hash]
+		lr.dirty[hash] = append(bucket[:len(bucket):len(bucket)], entry)
 	}
 
-	// Atomically create and store if absent (matches Java's computeIfAbsent)
-	// Note: factory may be called multiple times in race conditions, but only
-	// one result will be stored. Factory should be pure (no side effects).
-	newValue := lr.factory(labelValues)
-	actual, _ := lr.registry.LoadOrStore(key, newValue)
-	return actual.(T)
+	lr.misses++
+	if lr.misses >= len(lr.dirty) {
+		lr.promoteDirty()
+	}
+	return entry
+}
+
+// ensureDirty initializes the dirty table as a copy of read. Must be called with mu held.
+func (lr *LabelRegistry[T]) ensureDirty(read labelTable[T]) {
+	if lr.dirty == nil {
+		lr.dirty = make(labelTable[T], len(read)+1)
+		for hash, bucket := range read {
+			lr.dirty[hash] = bucket
+		}
+	}
+}
+
+// promoteDirty publishes the dirty table as the new read table. Must be called with mu held.
+func (lr *LabelRegistry[T]) promoteDirty() {
+	if lr.dirty != nil {
+		dirty := lr.dirty
+		lr.read.Store(&dirty)
+		lr.dirty = nil
+	}
+	lr.misses = 0
+}
+
+// Delete removes the metric instance for the given label values.
+// Bound handles that refer to the removed instance transparently re-create it on their next update.
+// It reports whether an instance was removed.
+func (lr *LabelRegistry[T]) Delete(labelValues []string) bool {
+	hash := labelValuesHash(lr.seed, labelValues)
+
+	lr.mu.Lock()
+	defer lr.mu.Unlock()
+
+	lr.ensureDirty(*lr.read.Load())
+	bucket := lr.dirty[hash]
+	i := slices.IndexFunc(bucket, func(entry *labelEntry[T]) bool {
+		return slices.Equal(entry.labelValues, labelValues)
+	})
+	if i < 0 {
+		return false
+	}
+	entry := bucket[i]
+	if len(bucket) == 1 {
+		delete(lr.dirty, hash)
+	} else {
+		lr.dirty[hash] = slices.Delete(slices.Clone(bucket), i, i+1)
+	}
+	// Publish immediately so that lookups stop returning the removed entry
+	lr.promoteDirty()
+	entry.removed.Store(true)
+	return true
 }
 
 // All returns an iterator over all metric instances in the registry.
 // This is used by the emitter to iterate over all label combinations.
 func (lr *LabelRegistry[T]) All() iter.Seq[T] {
 	return func(yield func(T) bool) {
-		lr.registry.Range(func(key, value any) bool {
-			return yield(value.(T))
-		})
+		// Publish pending entries so that the snapshot is complete
+		lr.mu.Lock()
+		lr.promoteDirty()
+		table := *lr.read.Load()
+		lr.mu.Unlock()
+
+		for _, bucket := range table {
+			for _, entry := range bucket {
+				if !yield(entry.value) {
+					return
+				}
+			}
+		}
 	}
 }
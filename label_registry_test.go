@@ -0,0 +1,104 @@
+package gcpmetrics
+
+import (
+	"strings"
+	"sync"
+	"testing"
+)
+
+// joinedKeyRegistry is the previous LabelRegistry implementation, kept as a benchmark baseline.
+// It joins the label values into a string key and stores instances in a sync.Map.
+type joinedKeyRegistry[T any] struct {
+	registry sync.Map
+	factory  func(labelValues []string) T
+}
+
+func (r *joinedKeyRegistry[T]) Get(labelValues []string) T {
+	key := strings.Join(labelValues, "\x00")
+	if value, ok := r.registry.Load(key); ok {
+		return value.(T)
+	}
+	actual, _ := r.registry.LoadOrStore(key, r.factory(labelValues))
+	return actual.(T)
+}
+
+func TestLabelRegistry_GetDoesNotAllocate(t *testing.T) {
+	counter := NewDynamicCounter("requests", nil, "status", "method")
+	counter.Inc("200", "GET")
+
+	allocs := testing.AllocsPerRun(1000, func() {
+		counter.Inc("200", "GET")
+	})
+	if allocs != 0 {
+		t.Errorf("expected 0 allocations per Inc, got %v", allocs)
+	}
+}
+
+func TestLabelRegistry_Collisions(t *testing.T) {
+	registry := newLabelRegistry([]string{"a", "b"}, func(vals []string) *StaticCounter {
+		return NewStaticCounter("test", labelValuesToMap([]string{"a", "b"}, vals))
+	})
+
+	// Force every combination into the same bucket to exercise collision handling
+	combos := [][]string{{"x", "y"}, {"y", "x"}, {"x", ""}, {"", "x"}, {"xy", ""}}
+	for _, combo := range combos {
+		entry := registry.getEntry(combo)
+		hash := labelValuesHash(registry.seed, combo)
+		registry.mu.Lock()
+		registry.ensureDirty(*registry.read.Load())
+		delete(registry.dirty, hash)
+		registry.dirty[0] = append(registry.dirty[0], entry)
+		registry.promoteDirty()
+		registry.mu.Unlock()
+	}
+
+	for i, combo := range combos {
+		if entry := (*registry.read.Load()).lookup(0, combo); entry == nil {
+			t.Errorf("combination %d %q not found", i, combo)
+		} else {
+			entry.value.Add(int64(i + 1))
+		}
+	}
+	for i, combo := range combos {
+		if v := (*registry.read.Load()).lookup(0, combo).value.Value(); v != int64(i+1) {
+			t.Errorf("combination %q: expected %d, got %d", combo, i+1, v)
+		}
+	}
+}
+
+func BenchmarkLabelRegistry_Get(b *testing.B) {
+	factory := func(vals []string) *StaticCounter {
+		return NewStaticCounter("requests", nil)
+	}
+	values := []string{"200", "GET", "/api/users"}
+
+	b.Run("hashed", func(b *testing.B) {
+		registry := newLabelRegistry([]string{"status", "method", "endpoint"}, factory)
+		registry.Get(values)
+		b.ReportAllocs()
+		b.RunParallel(func(pb *testing.PB) {
+			for pb.Next() {
+				registry.Get(values).Inc()
+			}
+		})
+	})
+
+	b.Run("joined-key", func(b *testing.B) {
+		registry := &joinedKeyRegistry[*StaticCounter]{factory: factory}
+		registry.Get(values)
+		b.ReportAllocs()
+		b.RunParallel(func(pb *testing.PB) {
+			for pb.Next() {
+				registry.Get(values).Inc()
+			}
+		})
+	})
+}
+
+func BenchmarkDynamicCounter_Inc(b *testing.B) {
+	counter := NewDynamicCounter("requests", nil, "status", "method")
+	b.ReportAllocs()
+	for b.Loop() {
+		counter.Inc("200", "GET")
+	}
+}
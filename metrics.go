@@ -1,5 +1,13 @@
 package gcpmetrics
 
+import (
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/nikolaybotev/go-gcp-metrics/clock"
+)
+
 // MetricsCollector defines the public interface for metrics implementations.
 type MetricsCollector interface {
 	// Counter creates a counter with optional static labels and dynamic label keys.
@@ -14,8 +22,16 @@ type MetricsCollector interface {
 	AddBeforeEmitListener(listener func())
 }
 
+// ShardedCounterCollector is implemented by MetricsCollector implementations that support sharded counters.
+// It is separate from MetricsCollector so that existing implementations need not provide it; use a type
+// assertion to check for support.
+type ShardedCounterCollector interface {
+	// ShardedCounter creates a counter that spreads increments across per-CPU cells to avoid contention.
+	ShardedCounter(name string, labels map[string]string, labelKeys ...string) Counter
+}
+
 // Metrics contains the backend-agnostic functionality shared by all Metrics implementations.
-// It implements the MetricsCollector interface and can be embedded by backend-specific implementations.
+// It implements the MetricsCollector and ShardedCounterCollector interfaces and can be embedded by backend-specific implementations.
 type Metrics struct {
 	// Static label metrics
 	Counters      []*StaticCounter
@@ -27,6 +43,18 @@ type Metrics struct {
 	DynamicGauges        []*DynamicGauge
 	// Lifecycle
 	BeforeEmitListeners []func()
+	// Label arity checking, applied to dynamic metrics when they are created
+	LabelArityMode         LabelArityMode
+	LabelArityErrorHandler func(err error)
+	// Self-metrics, emitted alongside the user-defined metrics once they have a value
+	LabelArityViolations *DynamicCounter
+	MissedEmits          *DynamicCounter
+	// Clock is the source of time for collected snapshots. Defaults to clock.System.
+	Clock clock.Clock
+	// Collection state
+	collectMu   sync.Mutex
+	created     time.Time // Start of cumulative intervals; guarded by collectMu
+	lastCollect time.Time // Start of the next delta interval; guarded by collectMu
 }
 
 // NewMetrics creates a new Metrics instance.
@@ -39,6 +67,8 @@ func NewMetrics() *Metrics {
 		DynamicDistributions: []*DynamicDistribution{},
 		DynamicGauges:        []*DynamicGauge{},
 		BeforeEmitListeners:  []func(){},
+		LabelArityViolations: NewDynamicCounter(labelArityViolationsMetric, nil, "metric"),
+		MissedEmits:          NewDynamicCounter(missedEmitsMetric, nil),
 	}
 }
 
@@ -46,12 +76,30 @@ func NewMetrics() *Metrics {
 // If labelKeys is empty, returns a StaticCounter; otherwise returns a DynamicCounter.
 // Both implement the Counter interface.
 func (me *Metrics) Counter(name string, labels map[string]string, labelKeys ...string) Counter {
+	me.markStarted()
 	if len(labelKeys) == 0 {
 		counter := NewStaticCounter(name, labels)
 		me.Counters = append(me.Counters, counter)
 		return counter
 	}
 	counter := NewDynamicCounter(name, labels, labelKeys...)
+	counter.configure(me.LabelArityMode, me.LabelArityErrorHandler, me.LabelArityViolations)
+	me.DynamicCounters = append(me.DynamicCounters, counter)
+	return counter
+}
+
+// ShardedCounter creates a counter like Counter, but backed by sharded storage that
+// spreads increments across padded per-CPU cells and sums them on collection.
+// Use it for counters incremented from many goroutines on hot paths.
+func (me *Metrics) ShardedCounter(name string, labels map[string]string, labelKeys ...string) Counter {
+	me.markStarted()
+	if len(labelKeys) == 0 {
+		counter := NewShardedStaticCounter(name, labels)
+		me.Counters = append(me.Counters, counter)
+		return counter
+	}
+	counter := NewShardedDynamicCounter(name, labels, labelKeys...)
+	counter.configure(me.LabelArityMode, me.LabelArityErrorHandler, me.LabelArityViolations)
 	me.DynamicCounters = append(me.DynamicCounters, counter)
 	return counter
 }
@@ -60,12 +108,14 @@ func (me *Metrics) Counter(name string, labels map[string]string, labelKeys ...s
 // If labelKeys is empty, returns a StaticGauge; otherwise returns a DynamicGauge.
 // Both implement the Gauge interface.
 func (me *Metrics) Gauge(name string, labels map[string]string, labelKeys ...string) Gauge {
+	me.markStarted()
 	if len(labelKeys) == 0 {
 		gauge := NewStaticGauge(name, labels)
 		me.Gauges = append(me.Gauges, gauge)
 		return gauge
 	}
 	gauge := NewDynamicGauge(name, labels, labelKeys...)
+	gauge.configure(me.LabelArityMode, me.LabelArityErrorHandler, me.LabelArityViolations)
 	me.DynamicGauges = append(me.DynamicGauges, gauge)
 	return gauge
 }
@@ -81,12 +131,14 @@ func (me *Metrics) Distribution(
 	labels map[string]string,
 	labelKeys ...string,
 ) Distribution {
+	me.markStarted()
 	if len(labelKeys) == 0 {
 		dist := NewStaticDistribution(name, unit, step, numBuckets, labels)
 		me.Distributions = append(me.Distributions, dist)
 		return dist
 	}
 	dist := NewDynamicDistribution(name, unit, step, numBuckets, labels, labelKeys...)
+	dist.configure(me.LabelArityMode, me.LabelArityErrorHandler, me.LabelArityViolations)
 	me.DynamicDistributions = append(me.DynamicDistributions, dist)
 	return dist
 }
@@ -104,3 +156,21 @@ func (m *Metrics) notifyBeforeEmitListeners() {
 		}
 	}
 }
+
+// allDynamicCounters returns the user-defined dynamic counters followed by the library's self-metrics.
+func (m *Metrics) allDynamicCounters() []*DynamicCounter {
+	counters := slices.Clip(m.DynamicCounters)
+	for _, selfMetric := range []*DynamicCounter{m.LabelArityViolations, m.MissedEmits} {
+		if selfMetric != nil {
+			counters = append(counters, selfMetric)
+		}
+	}
+	return counters
+}
+
+// markStarted records the registration time of the first metric as the start of cumulative intervals.
+func (m *Metrics) markStarted() {
+	m.collectMu.Lock()
+	defer m.collectMu.Unlock()
+	m.startTime()
+}
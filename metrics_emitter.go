@@ -5,32 +5,22 @@ import (
 	"time"
 )
 
+// MetricsEmitter delivers collected metric values to a backend.
 type MetricsEmitter interface {
-	Emit(ctx context.Context, metrics *Metrics)
+	// Emit delivers the snapshot. The snapshot may be shared with other emitters and must not be modified.
+	Emit(ctx context.Context, snapshot *Snapshot)
 }
 
 // ScheduleMetricsEmit schedules the emitter to emit metrics at the given interval in a new goroutine.
-// It returns a ticker that can be used to stop the scheduled emissions.
+// Emissions stop when ctx is cancelled. It returns an EmitScheduler that can be used to stop the
+// scheduled emissions, or to shut them down with a final flush.
+// opts may be nil.
 func ScheduleMetricsEmit(
 	ctx context.Context,
 	metrics *Metrics,
 	interval time.Duration,
 	emitter MetricsEmitter,
-) *time.Ticker {
-	ticker := time.NewTicker(interval)
-	go func() {
-		for {
-			select {
-			case <-ticker.C:
-				// Notify before emit listeners
-				metrics.notifyBeforeEmitListeners()
-				// Emit metrics
-				emitter.Emit(ctx, metrics)
-			case <-ctx.Done():
-				ticker.Stop()
-				return
-			}
-		}
-	}()
-	return ticker
+	opts *ScheduleOptions,
+) *EmitScheduler {
+	return newEmitScheduler(ctx, metrics, interval, emitter, opts)
 }
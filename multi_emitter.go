@@ -0,0 +1,71 @@
+package gcpmetrics
+
+import (
+	"context"
+	"log"
+	"sync"
+	"sync/atomic"
+)
+
+// MultiEmitter is a MetricsEmitter that delivers the same snapshot to several emitters.
+//
+// The snapshot is collected once per emission by the caller, so distributions are cleared exactly once.
+// Each emitter receives its own clone of the snapshot, so emitters cannot corrupt each other's data.
+//
+// Emitters run concurrently. Emit returns once every emitter has finished or ctx is done.
+// An emitter that is still running from a previous Emit is skipped, so a slow backend
+// neither blocks the others nor accumulates goroutines. Panics in an emitter are recovered.
+type MultiEmitter struct {
+	ErrorLogger *log.Logger
+	targets     []*multiEmitterTarget
+}
+
+// multiEmitterTarget tracks whether an emitter is still running.
+type multiEmitterTarget struct {
+	emitter MetricsEmitter
+	busy    atomic.Bool
+}
+
+// NewMultiEmitter creates a new MultiEmitter delivering to the given emitters.
+func NewMultiEmitter(emitters ...MetricsEmitter) *MultiEmitter {
+	targets := make([]*multiEmitterTarget, len(emitters))
+	for i, emitter := range emitters {
+		targets[i] = &multiEmitterTarget{emitter: emitter}
+	}
+	return &MultiEmitter{
+		ErrorLogger: log.Default(),
+		targets:     targets,
+	}
+}
+
+// Emit delivers the snapshot to every emitter.
+func (me *MultiEmitter) Emit(ctx context.Context, snapshot *Snapshot) {
+	var wg sync.WaitGroup
+	for i, target := range me.targets {
+		if !target.busy.CompareAndSwap(false, true) {
+			me.ErrorLogger.Printf("skipping emitter %d (%T): previous emit still in progress", i, target.emitter)
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer target.busy.Store(false)
+			defer func() {
+				if r := recover(); r != nil {
+					me.ErrorLogger.Printf("emitter %d (%T) panicked: %v", i, target.emitter, r)
+				}
+			}()
+			target.emitter.Emit(ctx, snapshot.Clone())
+		}()
+	}
+
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+	}
+}
@@ -0,0 +1,99 @@
+package gcpmetrics
+
+import (
+	"context"
+	"io"
+	"log"
+	"runtime"
+	"testing"
+	"time"
+)
+
+// capturingEmitter records the counter value and distribution sample count of each emit.
+type capturingEmitter struct {
+	counters chan int64
+	samples  chan int64
+	block    chan struct{}
+}
+
+func newCapturingEmitter() *capturingEmitter {
+	return &capturingEmitter{counters: make(chan int64, 10), samples: make(chan int64, 10)}
+}
+
+func (e *capturingEmitter) Emit(ctx context.Context, snapshot *Snapshot) {
+	if e.block != nil {
+		<-e.block
+	}
+	for _, point := range snapshot.Points {
+		switch point.ValueType {
+		case ValueTypeInt64:
+			e.counters <- point.Int64Value
+		case ValueTypeDistribution:
+			e.samples <- point.Distribution.NumSamples
+			// Modifying the snapshot must not affect other emitters
+			point.Distribution.NumSamples = -1
+		}
+	}
+}
+
+type panickingEmitter struct{}
+
+func (panickingEmitter) Emit(ctx context.Context, snapshot *Snapshot) {
+	panic("backend failure")
+}
+
+func TestMultiEmitter_DeliversSameSnapshot(t *testing.T) {
+	metrics := NewMetrics()
+	counter := metrics.Counter("requests", nil, "status")
+	dist := metrics.Distribution("latency", "ms", 10, 10, nil)
+	counter.Add(3, "200")
+	dist.Update(5)
+	dist.Update(15)
+
+	first, second := newCapturingEmitter(), newCapturingEmitter()
+	multi := NewMultiEmitter(first, panickingEmitter{}, second)
+	multi.ErrorLogger = log.New(io.Discard, "", 0)
+	multi.Emit(context.Background(), metrics.Collect())
+
+	for _, e := range []*capturingEmitter{first, second} {
+		if v := <-e.counters; v != 3 {
+			t.Errorf("expected counter value 3, got %d", v)
+		}
+		if n := <-e.samples; n != 2 {
+			t.Errorf("expected 2 distribution samples, got %d", n)
+		}
+	}
+	if n := dist.(*StaticDistribution).GetAndClear().NumSamples; n != 0 {
+		t.Errorf("expected the source distribution to be cleared once, got %d samples", n)
+	}
+}
+
+func TestMultiEmitter_SlowEmitterDoesNotBlockOthers(t *testing.T) {
+	metrics := NewMetrics()
+	metrics.Counter("requests", nil)
+	metrics.Distribution("latency", "ms", 10, 10, nil)
+
+	slow, fast := newCapturingEmitter(), newCapturingEmitter()
+	slow.block = make(chan struct{})
+	multi := NewMultiEmitter(slow, fast)
+	multi.ErrorLogger = log.New(io.Discard, "", 0)
+
+	for range 2 {
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		multi.Emit(ctx, metrics.Collect())
+		cancel()
+	}
+	if n := len(fast.counters); n != 2 {
+		t.Errorf("expected fast emitter to receive 2 emits, got %d", n)
+	}
+
+	// Once the first emit completes, the slow emitter receives the next emit
+	close(slow.block)
+	for multi.targets[0].busy.Load() {
+		runtime.Gosched()
+	}
+	multi.Emit(context.Background(), metrics.Collect())
+	if n := len(slow.counters); n != 2 {
+		t.Errorf("expected slow emitter to receive 2 emits after being skipped while busy, got %d", n)
+	}
+}
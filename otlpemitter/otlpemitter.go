@@ -0,0 +1,355 @@
+// Package otlpemitter provides a MetricsEmitter that exports metrics to an
+// OpenTelemetry Collector (or any OTLP receiver) over OTLP/gRPC or OTLP/HTTP.
+package otlpemitter
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"log"
+	"maps"
+	"net/http"
+	"slices"
+	"time"
+
+	gcpmetrics "github.com/nikolaybotev/go-gcp-metrics"
+	colmetricspb "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	metricspb "go.opentelemetry.io/proto/otlp/metrics/v1"
+	resourcepb "go.opentelemetry.io/proto/otlp/resource/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/proto"
+)
+
+// Protocol selects the OTLP transport.
+type Protocol int
+
+const (
+	// ProtocolGRPC exports over OTLP/gRPC.
+	ProtocolGRPC Protocol = iota
+	// ProtocolHTTP exports over OTLP/HTTP with protobuf encoding.
+	ProtocolHTTP
+)
+
+const (
+	// DefaultGRPCEndpoint is the default OTLP/gRPC receiver address.
+	DefaultGRPCEndpoint = "localhost:4317"
+	// DefaultHTTPEndpoint is the default OTLP/HTTP metrics receiver URL.
+	DefaultHTTPEndpoint = "http://localhost:4318/v1/metrics"
+	// defaultScopeName is the instrumentation scope reported with every export.
+	defaultScopeName = "github.com/nikolaybotev/go-gcp-metrics"
+)
+
+// Options contains optional configuration for Emitter.
+type Options struct {
+	Protocol Protocol
+	// Endpoint is the receiver address: host:port for gRPC, or the full metrics URL for HTTP.
+	// Defaults to DefaultGRPCEndpoint or DefaultHTTPEndpoint.
+	Endpoint string
+	// Insecure disables TLS for gRPC. HTTP uses TLS only for https:// endpoints.
+	Insecure bool
+	// Headers are sent with every export, e.g. for authentication.
+	Headers map[string]string
+	// ResourceAttributes describe the entity producing the metrics, e.g. service.name.
+	ResourceAttributes map[string]string
+	// MetricsNamePrefix is prepended to every metric name.
+	MetricsNamePrefix string
+	// HTTPClient is used for OTLP/HTTP. Defaults to a client with a 10s timeout.
+	HTTPClient *http.Client
+	// DialOptions are appended to the options used to create the gRPC client.
+	DialOptions []grpc.DialOption
+	ErrorLogger *log.Logger
+}
+
+// Emitter is a MetricsEmitter that converts snapshots into OTLP metric data:
+// counters become monotonic cumulative Sums, gauges become Gauges, and
+// distributions become delta Histograms with explicit bounds.
+//
+// Distribution bucket upper bounds are exclusive, while OTLP explicit bounds are inclusive.
+// Since samples are integers, a bucket bound b is exported as b-1, which holds exactly
+// the same samples.
+type Emitter struct {
+	protocol          Protocol
+	endpoint          string
+	headers           map[string]string
+	resource          *resourcepb.Resource
+	metricsNamePrefix string
+	httpClient        *http.Client
+	conn              *grpc.ClientConn
+	client            colmetricspb.MetricsServiceClient
+	errorLogger       *log.Logger
+}
+
+// New creates a new Emitter. opts may be nil.
+// For gRPC the connection is established lazily, so New does not block.
+func New(opts *Options) (*Emitter, error) {
+	// Set defaults if nil
+	if opts == nil {
+		opts = &Options{}
+	}
+	e := &Emitter{
+		protocol:          opts.Protocol,
+		endpoint:          opts.Endpoint,
+		headers:           opts.Headers,
+		resource:          &resourcepb.Resource{Attributes: attributes(opts.ResourceAttributes)},
+		metricsNamePrefix: opts.MetricsNamePrefix,
+		httpClient:        opts.HTTPClient,
+		errorLogger:       opts.ErrorLogger,
+	}
+	if e.errorLogger == nil {
+		e.errorLogger = log.Default()
+	}
+
+	switch opts.Protocol {
+	case ProtocolGRPC:
+		if e.endpoint == "" {
+			e.endpoint = DefaultGRPCEndpoint
+		}
+		creds := credentials.NewTLS(&tls.Config{})
+		if opts.Insecure {
+			creds = insecure.NewCredentials()
+		}
+		dialOptions := append([]grpc.DialOption{grpc.WithTransportCredentials(creds)}, opts.DialOptions...)
+		conn, err := grpc.NewClient(e.endpoint, dialOptions...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create OTLP gRPC client for %s: %w", e.endpoint, err)
+		}
+		e.conn = conn
+		e.client = colmetricspb.NewMetricsServiceClient(conn)
+	case ProtocolHTTP:
+		if e.endpoint == "" {
+			e.endpoint = DefaultHTTPEndpoint
+		}
+		if e.httpClient == nil {
+			e.httpClient = &http.Client{Timeout: 10 * time.Second}
+		}
+	default:
+		return nil, fmt.Errorf("unknown OTLP protocol %d", opts.Protocol)
+	}
+	return e, nil
+}
+
+// Close releases the gRPC connection, if any.
+func (e *Emitter) Close() error {
+	if e.conn != nil {
+		return e.conn.Close()
+	}
+	return nil
+}
+
+// Emit converts the snapshot to OTLP and exports it. Errors are logged to the ErrorLogger.
+func (e *Emitter) Emit(ctx context.Context, snapshot *gcpmetrics.Snapshot) {
+	req := e.buildRequest(snapshot)
+	if len(req.ResourceMetrics[0].ScopeMetrics[0].Metrics) == 0 {
+		return
+	}
+
+	var resp *colmetricspb.ExportMetricsServiceResponse
+	var err error
+	switch e.protocol {
+	case ProtocolGRPC:
+		resp, err = e.exportGRPC(ctx, req)
+	case ProtocolHTTP:
+		resp, err = e.exportHTTP(ctx, req)
+	}
+	if err != nil {
+		e.errorLogger.Printf("failed to export OTLP metrics to %s: %v", e.endpoint, err)
+		return
+	}
+	if partial := resp.GetPartialSuccess(); partial.GetRejectedDataPoints() > 0 {
+		e.errorLogger.Printf("OTLP receiver %s rejected %d data points: %s",
+			e.endpoint, partial.GetRejectedDataPoints(), partial.GetErrorMessage())
+	}
+}
+
+// exportGRPC sends the request over OTLP/gRPC.
+func (e *Emitter) exportGRPC(ctx context.Context, req *colmetricspb.ExportMetricsServiceRequest) (*colmetricspb.ExportMetricsServiceResponse, error) {
+	if len(e.headers) > 0 {
+		ctx = metadata.NewOutgoingContext(ctx, metadata.New(e.headers))
+	}
+	return e.client.Export(ctx, req)
+}
+
+// exportHTTP sends the request over OTLP/HTTP using protobuf encoding.
+func (e *Emitter) exportHTTP(ctx context.Context, req *colmetricspb.ExportMetricsServiceRequest) (*colmetricspb.ExportMetricsServiceResponse, error) {
+	body, err := proto.Marshal(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, e.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/x-protobuf")
+	for k, v := range e.headers {
+		httpReq.Header.Set(k, v)
+	}
+
+	httpResp, err := e.httpClient.Do(httpReq)
+	if err != nil {
+		return nil, err
+	}
+	defer httpResp.Body.Close()
+
+	respBody, err := io.ReadAll(httpResp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+	if httpResp.StatusCode < 200 || httpResp.StatusCode > 299 {
+		return nil, fmt.Errorf("HTTP status %d", httpResp.StatusCode)
+	}
+
+	resp := &colmetricspb.ExportMetricsServiceResponse{}
+	if err := proto.Unmarshal(respBody, resp); err != nil {
+		return nil, fmt.Errorf("failed to parse response: %w", err)
+	}
+	return resp, nil
+}
+
+// buildRequest converts a snapshot into an export request. Points with the same name are
+// grouped into a single metric. A point whose data type conflicts with an earlier point of the
+// same name is skipped and logged, since one metric cannot hold two data types.
+// Distributions without samples are skipped.
+func (e *Emitter) buildRequest(snapshot *gcpmetrics.Snapshot) *colmetricspb.ExportMetricsServiceRequest {
+	var metrics []*metricspb.Metric
+	byName := make(map[string]*metricspb.Metric)
+	typeByName := make(map[string]dataType)
+	conflicts := make(map[string]bool)
+
+	for _, point := range snapshot.Points {
+		if point.ValueType == gcpmetrics.ValueTypeDistribution && point.Distribution.NumSamples == 0 {
+			continue
+		}
+		name := e.metricsNamePrefix + point.Name
+		typ := pointDataType(point)
+		m, ok := byName[name]
+		if !ok {
+			m = newMetric(name, typ, point.Unit)
+			byName[name] = m
+			typeByName[name] = typ
+			metrics = append(metrics, m)
+		} else if typeByName[name] != typ {
+			if !conflicts[name] {
+				conflicts[name] = true
+				e.errorLogger.Printf("skipping %s points of OTLP metric %s, which already holds %s points", typ, name, typeByName[name])
+			}
+			continue
+		}
+		appendDataPoint(m, point)
+	}
+
+	return &colmetricspb.ExportMetricsServiceRequest{
+		ResourceMetrics: []*metricspb.ResourceMetrics{
+			{
+				Resource: e.resource,
+				ScopeMetrics: []*metricspb.ScopeMetrics{
+					{
+						Scope:   &commonpb.InstrumentationScope{Name: defaultScopeName},
+						Metrics: metrics,
+					},
+				},
+			},
+		},
+	}
+}
+
+// dataType is the OTLP data type a point is exported as.
+type dataType string
+
+const (
+	dataTypeHistogram dataType = "histogram"
+	dataTypeSum       dataType = "sum"
+	dataTypeGauge     dataType = "gauge"
+)
+
+// pointDataType returns the OTLP data type matching the point.
+func pointDataType(point gcpmetrics.Point) dataType {
+	switch {
+	case point.ValueType == gcpmetrics.ValueTypeDistribution:
+		return dataTypeHistogram
+	case point.Kind == gcpmetrics.MetricKindCumulative:
+		return dataTypeSum
+	default:
+		return dataTypeGauge
+	}
+}
+
+// newMetric creates an empty OTLP metric of the given data type.
+func newMetric(name string, typ dataType, unit string) *metricspb.Metric {
+	m := &metricspb.Metric{Name: name, Unit: unit}
+	switch typ {
+	case dataTypeHistogram:
+		m.Data = &metricspb.Metric_Histogram{Histogram: &metricspb.Histogram{
+			AggregationTemporality: metricspb.AggregationTemporality_AGGREGATION_TEMPORALITY_DELTA,
+		}}
+	case dataTypeSum:
+		m.Data = &metricspb.Metric_Sum{Sum: &metricspb.Sum{
+			AggregationTemporality: metricspb.AggregationTemporality_AGGREGATION_TEMPORALITY_CUMULATIVE,
+			IsMonotonic:            true,
+		}}
+	default:
+		m.Data = &metricspb.Metric_Gauge{Gauge: &metricspb.Gauge{}}
+	}
+	return m
+}
+
+// appendDataPoint adds the point to the metric's data points.
+func appendDataPoint(m *metricspb.Metric, point gcpmetrics.Point) {
+	attrs := attributes(point.Labels)
+	start := uint64(point.StartTime.UnixNano())
+	end := uint64(point.EndTime.UnixNano())
+
+	switch data := m.Data.(type) {
+	case *metricspb.Metric_Histogram:
+		dist := point.Distribution
+		sum := dist.Mean * float64(dist.NumSamples)
+		bucketCounts := make([]uint64, len(dist.Buckets))
+		for i, n := range dist.Buckets {
+			bucketCounts[i] = uint64(n)
+		}
+		// Samples below the exclusive bound are at most bound-1
+		bounds := make([]float64, len(point.BucketOptions.Bounds))
+		for i, bound := range point.BucketOptions.Bounds {
+			bounds[i] = bound - 1
+		}
+		data.Histogram.DataPoints = append(data.Histogram.DataPoints, &metricspb.HistogramDataPoint{
+			Attributes:        attrs,
+			StartTimeUnixNano: start,
+			TimeUnixNano:      end,
+			Count:             uint64(dist.NumSamples),
+			Sum:               &sum,
+			BucketCounts:      bucketCounts,
+			ExplicitBounds:    bounds,
+		})
+	case *metricspb.Metric_Sum:
+		data.Sum.DataPoints = append(data.Sum.DataPoints, &metricspb.NumberDataPoint{
+			Attributes:        attrs,
+			StartTimeUnixNano: start,
+			TimeUnixNano:      end,
+			Value:             &metricspb.NumberDataPoint_AsInt{AsInt: point.Int64Value},
+		})
+	case *metricspb.Metric_Gauge:
+		data.Gauge.DataPoints = append(data.Gauge.DataPoints, &metricspb.NumberDataPoint{
+			Attributes:   attrs,
+			TimeUnixNano: end,
+			Value:        &metricspb.NumberDataPoint_AsInt{AsInt: point.Int64Value},
+		})
+	}
+}
+
+// attributes converts labels into OTLP string attributes, sorted by key.
+func attributes(labels map[string]string) []*commonpb.KeyValue {
+	attrs := make([]*commonpb.KeyValue, 0, len(labels))
+	for _, k := range slices.Sorted(maps.Keys(labels)) {
+		attrs = append(attrs, &commonpb.KeyValue{
+			Key:   k,
+			Value: &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: labels[k]}},
+		})
+	}
+	return attrs
+}
@@ -0,0 +1,176 @@
+package otlpemitter
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+
+	gcpmetrics "github.com/nikolaybotev/go-gcp-metrics"
+	colmetricspb "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	metricspb "go.opentelemetry.io/proto/otlp/metrics/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/proto"
+)
+
+// receiver is a local OTLP metrics receiver that forwards requests to a channel.
+type receiver struct {
+	colmetricspb.UnimplementedMetricsServiceServer
+	requests chan *colmetricspb.ExportMetricsServiceRequest
+}
+
+func (r *receiver) Export(ctx context.Context, req *colmetricspb.ExportMetricsServiceRequest) (*colmetricspb.ExportMetricsServiceResponse, error) {
+	r.requests <- req
+	return &colmetricspb.ExportMetricsServiceResponse{}, nil
+}
+
+func newTestSnapshot() *gcpmetrics.Snapshot {
+	metrics := gcpmetrics.NewMetrics()
+	metrics.Counter("requests", nil, "status").Add(3, "200")
+	metrics.Gauge("temperature", map[string]string{"location": "dc1"}).Set(25)
+	latency := metrics.Distribution("latency", "ms", 100, 2, nil)
+	latency.Update(50)
+	latency.Update(150)
+	return metrics.Collect()
+}
+
+// checkRequest verifies the conversion of newTestSnapshot.
+func checkRequest(t *testing.T, req *colmetricspb.ExportMetricsServiceRequest) {
+	t.Helper()
+	metrics := req.GetResourceMetrics()[0].GetScopeMetrics()[0].GetMetrics()
+	if len(metrics) != 3 {
+		t.Fatalf("expected 3 metrics, got %d", len(metrics))
+	}
+
+	sum := metrics[0].GetSum()
+	if metrics[0].GetName() != "app/requests" || sum == nil || !sum.GetIsMonotonic() ||
+		sum.GetAggregationTemporality() != metricspb.AggregationTemporality_AGGREGATION_TEMPORALITY_CUMULATIVE {
+		t.Errorf("unexpected counter metric %v", metrics[0])
+	} else if dp := sum.GetDataPoints()[0]; dp.GetAsInt() != 3 || dp.GetAttributes()[0].GetValue().GetStringValue() != "200" {
+		t.Errorf("unexpected counter data point %v", dp)
+	}
+
+	if gauge := metrics[1].GetGauge(); gauge == nil || gauge.GetDataPoints()[0].GetAsInt() != 25 {
+		t.Errorf("unexpected gauge metric %v", metrics[1])
+	}
+
+	hist := metrics[2].GetHistogram()
+	if hist == nil || metrics[2].GetUnit() != "ms" ||
+		hist.GetAggregationTemporality() != metricspb.AggregationTemporality_AGGREGATION_TEMPORALITY_DELTA {
+		t.Fatalf("unexpected distribution metric %v", metrics[2])
+	}
+	dp := hist.GetDataPoints()[0]
+	if dp.GetCount() != 2 || dp.GetSum() != 200 || len(dp.GetExplicitBounds()) != 3 || len(dp.GetBucketCounts()) != 4 {
+		t.Errorf("unexpected histogram data point %v", dp)
+	}
+}
+
+func TestEmitter_GRPC(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	recv := &receiver{requests: make(chan *colmetricspb.ExportMetricsServiceRequest, 1)}
+	server := grpc.NewServer()
+	colmetricspb.RegisterMetricsServiceServer(server, recv)
+	go server.Serve(listener)
+	defer server.Stop()
+
+	emitter, err := New(&Options{
+		Protocol:          ProtocolGRPC,
+		Endpoint:          listener.Addr().String(),
+		Insecure:          true,
+		MetricsNamePrefix: "app/",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer emitter.Close()
+
+	emitter.Emit(context.Background(), newTestSnapshot())
+	checkRequest(t, <-recv.requests)
+}
+
+func TestEmitter_HTTP(t *testing.T) {
+	requests := make(chan *colmetricspb.ExportMetricsServiceRequest, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if ct := r.Header.Get("Content-Type"); ct != "application/x-protobuf" {
+			t.Errorf("unexpected content type %q", ct)
+		}
+		if auth := r.Header.Get("Authorization"); auth != "Bearer token" {
+			t.Errorf("unexpected authorization header %q", auth)
+		}
+		body, _ := io.ReadAll(r.Body)
+		req := &colmetricspb.ExportMetricsServiceRequest{}
+		if err := proto.Unmarshal(body, req); err != nil {
+			t.Errorf("failed to parse request: %v", err)
+		}
+		requests <- req
+		resp, _ := proto.Marshal(&colmetricspb.ExportMetricsServiceResponse{})
+		w.Header().Set("Content-Type", "application/x-protobuf")
+		w.Write(resp)
+	}))
+	defer server.Close()
+
+	emitter, err := New(&Options{
+		Protocol:          ProtocolHTTP,
+		Endpoint:          server.URL + "/v1/metrics",
+		Headers:           map[string]string{"Authorization": "Bearer token"},
+		MetricsNamePrefix: "app/",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	emitter.Emit(context.Background(), newTestSnapshot())
+	checkRequest(t, <-requests)
+}
+
+func TestEmitter_ConflictingDataTypes(t *testing.T) {
+	metrics := gcpmetrics.NewMetrics()
+	metrics.Counter("queue", nil).Add(3)
+	metrics.Gauge("queue", nil).Set(7)
+
+	var logged bytes.Buffer
+	e, err := New(&Options{Protocol: ProtocolHTTP, ErrorLogger: log.New(&logged, "", 0)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	req := e.buildRequest(metrics.Collect())
+
+	ms := req.ResourceMetrics[0].ScopeMetrics[0].Metrics
+	if len(ms) != 1 || len(ms[0].GetSum().GetDataPoints()) != 1 || ms[0].GetGauge() != nil {
+		t.Errorf("expected a single sum metric, got %v", ms)
+	}
+	if !strings.Contains(logged.String(), "skipping gauge points of OTLP metric queue") {
+		t.Errorf("expected the conflict to be logged, got %q", logged.String())
+	}
+}
+
+func TestEmitter_SampleOnBucketBound(t *testing.T) {
+	metrics := gcpmetrics.NewMetrics()
+	latency := metrics.Distribution("latency", "ms", 100, 2, nil)
+	// On a bucket bound, which is exclusive
+	latency.Update(100)
+
+	e, err := New(&Options{Protocol: ProtocolHTTP})
+	if err != nil {
+		t.Fatal(err)
+	}
+	req := e.buildRequest(metrics.Collect())
+
+	dp := req.ResourceMetrics[0].ScopeMetrics[0].Metrics[0].GetHistogram().GetDataPoints()[0]
+	// OTLP bucket i holds the samples in (bounds[i-1], bounds[i]], so 100 is in (99, 199]
+	if bounds := dp.GetExplicitBounds(); !slices.Equal(bounds, []float64{-1, 99, 199}) {
+		t.Errorf("expected bounds [-1 99 199], got %v", bounds)
+	}
+	if counts := dp.GetBucketCounts(); !slices.Equal(counts, []uint64{0, 0, 1, 0}) {
+		t.Errorf("expected bucket counts [0 0 1 0], got %v", counts)
+	}
+}
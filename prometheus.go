@@ -0,0 +1,257 @@
+package gcpmetrics
+
+import (
+	"bufio"
+	"cmp"
+	"context"
+	"fmt"
+	"maps"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/nikolaybotev/go-gcp-metrics/iterutil"
+)
+
+// prometheusContentType is the content type of the Prometheus text exposition format.
+const prometheusContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// PrometheusOptions contains optional configuration for PrometheusExporter.
+type PrometheusOptions struct {
+	// Namespace is prepended to every metric name, separated by an underscore.
+	Namespace string
+	// CommonLabels are added to every exported series.
+	CommonLabels map[string]string
+}
+
+// PrometheusExporter serves the contents of a Metrics in the Prometheus text exposition format.
+//
+// Counters are exported as counter, gauges as gauge, and distributions as histogram with
+// cumulative le buckets. Counters and gauges are read on every scrape. Distributions are
+// cleared whenever a snapshot is collected, so the exporter never reads them directly: it is
+// also a MetricsEmitter, and accumulates the distribution samples of every snapshot it receives
+// into cumulative histograms. Histograms are therefore only exported once snapshots are delivered
+// to the exporter. With GcpMetrics, pass the exporter to EmitEvery:
+//
+//	exporter := NewPrometheusExporter(gcpMetrics.Metrics, nil)
+//	scheduler := gcpMetrics.EmitEvery(ctx, interval, exporter)
+//
+// Otherwise, schedule the exporter with ScheduleMetricsEmit, in a MultiEmitter if it shares the
+// schedule with other emitters.
+//
+// Distribution bucket upper bounds are exclusive, while Prometheus le bounds are inclusive.
+// Since samples are integers, a bucket bound b is exported as le="b-1", which holds exactly
+// the same samples.
+type PrometheusExporter struct {
+	metrics      *Metrics
+	namespace    string
+	commonLabels map[string]string
+	mu           sync.Mutex
+	histograms   map[string]*prometheusHistogram // Guarded by mu; keyed by series
+}
+
+// prometheusHistogram is a cumulative histogram accumulated from distribution snapshots.
+type prometheusHistogram struct {
+	name   string
+	labels map[string]string
+	bounds []float64
+	counts []int64 // Non-cumulative, one per bucket including underflow and overflow
+	count  int64
+	sum    float64
+}
+
+// NewPrometheusExporter creates a new PrometheusExporter for the given Metrics. opts may be nil.
+func NewPrometheusExporter(metrics *Metrics, opts *PrometheusOptions) *PrometheusExporter {
+	if opts == nil {
+		opts = &PrometheusOptions{}
+	}
+	return &PrometheusExporter{
+		metrics:      metrics,
+		namespace:    opts.Namespace,
+		commonLabels: opts.CommonLabels,
+		histograms:   make(map[string]*prometheusHistogram),
+	}
+}
+
+// Emit accumulates the distribution points of the snapshot into cumulative histograms.
+func (pe *PrometheusExporter) Emit(ctx context.Context, snapshot *Snapshot) {
+	pe.mu.Lock()
+	defer pe.mu.Unlock()
+
+	for _, point := range snapshot.Points {
+		if point.ValueType != ValueTypeDistribution || point.Distribution.NumSamples == 0 {
+			continue
+		}
+		name := pe.metricName(point.Name)
+		key := prometheusSeriesKey(name, point.Labels)
+		h, ok := pe.histograms[key]
+		if !ok || len(h.counts) != len(point.Distribution.Buckets) {
+			h = &prometheusHistogram{
+				name:   name,
+				labels: maps.Clone(point.Labels),
+				bounds: point.BucketOptions.Bounds,
+				counts: make([]int64, len(point.Distribution.Buckets)),
+			}
+			pe.histograms[key] = h
+		}
+		for i, n := range point.Distribution.Buckets {
+			h.counts[i] += n
+		}
+		h.count += point.Distribution.NumSamples
+		h.sum += point.Distribution.Mean * float64(point.Distribution.NumSamples)
+	}
+}
+
+// ServeHTTP writes the metrics in the Prometheus text exposition format.
+func (pe *PrometheusExporter) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", prometheusContentType)
+	bw := bufio.NewWriter(w)
+	pe.write(bw)
+	bw.Flush()
+}
+
+// prometheusSeries is one exported sample line.
+type prometheusSeries struct {
+	labels map[string]string
+	value  string
+}
+
+// write renders all metric families, sorted by name, to w.
+func (pe *PrometheusExporter) write(w *bufio.Writer) {
+	counters := make(map[string][]prometheusSeries)
+	for c := range iterutil.CombineMetrics(pe.metrics.Counters, pe.metrics.allDynamicCounters()) {
+		name := pe.metricName(c.Name)
+		if !strings.HasSuffix(name, "_total") {
+			name += "_total"
+		}
+		counters[name] = append(counters[name], prometheusSeries{c.Labels, strconv.FormatInt(c.Value(), 10)})
+	}
+	gauges := make(map[string][]prometheusSeries)
+	for g := range iterutil.CombineMetrics(pe.metrics.Gauges, pe.metrics.DynamicGauges) {
+		name := pe.metricName(g.Name)
+		gauges[name] = append(gauges[name], prometheusSeries{g.Labels, strconv.FormatInt(g.Value(), 10)})
+	}
+
+	for _, name := range slices.Sorted(maps.Keys(counters)) {
+		pe.writeFamily(w, name, "counter", counters[name])
+	}
+	for _, name := range slices.Sorted(maps.Keys(gauges)) {
+		pe.writeFamily(w, name, "gauge", gauges[name])
+	}
+
+	pe.mu.Lock()
+	defer pe.mu.Unlock()
+	histograms := slices.SortedFunc(maps.Values(pe.histograms), func(a, b *prometheusHistogram) int {
+		return cmp.Or(strings.Compare(a.name, b.name), strings.Compare(prometheusLabelsKey(a.labels), prometheusLabelsKey(b.labels)))
+	})
+	for i, h := range histograms {
+		if i == 0 || histograms[i-1].name != h.name {
+			fmt.Fprintf(w, "# TYPE %s histogram\n", h.name)
+		}
+		var cumulative int64
+		for j, bound := range h.bounds {
+			cumulative += h.counts[j]
+			// Samples below the exclusive bound are at most bound-1
+			le := strconv.FormatFloat(bound-1, 'g', -1, 64)
+			pe.writeSample(w, h.name+"_bucket", h.labels, "le", le, strconv.FormatInt(cumulative, 10))
+		}
+		pe.writeSample(w, h.name+"_bucket", h.labels, "le", "+Inf", strconv.FormatInt(h.count, 10))
+		pe.writeSample(w, h.name+"_sum", h.labels, "", "", strconv.FormatFloat(h.sum, 'g', -1, 64))
+		pe.writeSample(w, h.name+"_count", h.labels, "", "", strconv.FormatInt(h.count, 10))
+	}
+}
+
+// writeFamily writes the TYPE line and the samples of one counter or gauge family.
+func (pe *PrometheusExporter) writeFamily(w *bufio.Writer, name, typ string, series []prometheusSeries) {
+	slices.SortFunc(series, func(a, b prometheusSeries) int {
+		return strings.Compare(prometheusLabelsKey(a.labels), prometheusLabelsKey(b.labels))
+	})
+	fmt.Fprintf(w, "# TYPE %s %s\n", name, typ)
+	for _, s := range series {
+		pe.writeSample(w, name, s.labels, "", "", s.value)
+	}
+}
+
+// writeSample writes one sample line with the common labels, the series labels and an optional extra label.
+func (pe *PrometheusExporter) writeSample(w *bufio.Writer, name string, labels map[string]string, extraKey, extraValue, value string) {
+	merged := make(map[string]string, len(pe.commonLabels)+len(labels)+1)
+	for k, v := range pe.commonLabels {
+		merged[prometheusLabelName(k)] = v
+	}
+	for k, v := range labels {
+		merged[prometheusLabelName(k)] = v
+	}
+	if extraKey != "" {
+		merged[extraKey] = extraValue
+	}
+
+	w.WriteString(name)
+	if len(merged) > 0 {
+		w.WriteByte('{')
+		for i, k := range slices.Sorted(maps.Keys(merged)) {
+			if i > 0 {
+				w.WriteByte(',')
+			}
+			w.WriteString(k)
+			w.WriteString(`="`)
+			w.WriteString(prometheusLabelValueReplacer.Replace(merged[k]))
+			w.WriteByte('"')
+		}
+		w.WriteByte('}')
+	}
+	w.WriteByte(' ')
+	w.WriteString(value)
+	w.WriteByte('\n')
+}
+
+// metricName returns the sanitized metric name with the namespace prepended.
+func (pe *PrometheusExporter) metricName(name string) string {
+	if pe.namespace != "" {
+		name = pe.namespace + "_" + name
+	}
+	return prometheusName(name)
+}
+
+// prometheusLabelValueReplacer escapes label values as required by the text exposition format.
+var prometheusLabelValueReplacer = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// prometheusName replaces characters that are not valid in Prometheus metric and label names with underscores.
+func prometheusName(name string) string {
+	var b strings.Builder
+	b.Grow(len(name))
+	for i, r := range name {
+		switch {
+		case r == '_' || r == ':' || (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z'):
+			b.WriteRune(r)
+		case r >= '0' && r <= '9' && i > 0:
+			b.WriteRune(r)
+		default:
+			b.WriteByte('_')
+		}
+	}
+	return b.String()
+}
+
+// prometheusLabelName sanitizes a label name. Unlike metric names, label names may not contain colons.
+func prometheusLabelName(name string) string {
+	return strings.ReplaceAll(prometheusName(name), ":", "_")
+}
+
+// prometheusLabelsKey returns a canonical string for a label set, used for sorting and map keys.
+func prometheusLabelsKey(labels map[string]string) string {
+	var b strings.Builder
+	for _, k := range slices.Sorted(maps.Keys(labels)) {
+		b.WriteString(k)
+		b.WriteByte(0)
+		b.WriteString(labels[k])
+		b.WriteByte(0)
+	}
+	return b.String()
+}
+
+// prometheusSeriesKey identifies a histogram series by name and labels.
+func prometheusSeriesKey(name string, labels map[string]string) string {
+	return name + "\x00" + prometheusLabelsKey(labels)
+}
@@ -0,0 +1,132 @@
+package gcpmetrics
+
+import (
+	"context"
+	"io"
+	"log"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nikolaybotev/go-gcp-metrics/clock/clocktest"
+)
+
+func TestPrometheusExporter(t *testing.T) {
+	metrics := NewMetrics()
+	requests := metrics.Counter("http_requests", nil, "status")
+	temperature := metrics.Gauge("temperature", map[string]string{"location": "dc\"1\""})
+	latency := metrics.Distribution("latency", "ms", 100, 2, nil)
+	requests.Add(3, "200")
+	requests.Inc("500")
+	temperature.Set(25)
+	latency.Update(50)
+	latency.Update(150)
+	latency.Update(500)
+	// On a bucket bound, which is exclusive
+	latency.Update(100)
+
+	exporter := NewPrometheusExporter(metrics, &PrometheusOptions{
+		Namespace:    "app",
+		CommonLabels: map[string]string{"instance": "host-1"},
+	})
+	exporter.Emit(context.Background(), metrics.Collect())
+	// A second collection adds to the cumulative histogram
+	latency.Update(150)
+	exporter.Emit(context.Background(), metrics.Collect())
+
+	recorder := httptest.NewRecorder()
+	exporter.ServeHTTP(recorder, httptest.NewRequest("GET", "/metrics", nil))
+
+	if ct := recorder.Header().Get("Content-Type"); ct != prometheusContentType {
+		t.Errorf("unexpected content type %q", ct)
+	}
+	expected := `# TYPE app_http_requests_total counter
+app_http_requests_total{instance="host-1",status="200"} 3
+app_http_requests_total{instance="host-1",status="500"} 1
+# TYPE app_temperature gauge
+app_temperature{instance="host-1",location="dc\"1\""} 25
+# TYPE app_latency histogram
+app_latency_bucket{instance="host-1",le="-1"} 0
+app_latency_bucket{instance="host-1",le="99"} 1
+app_latency_bucket{instance="host-1",le="199"} 4
+app_latency_bucket{instance="host-1",le="+Inf"} 5
+app_latency_sum{instance="host-1"} 950
+app_latency_count{instance="host-1"} 5
+`
+	if body := recorder.Body.String(); body != expected {
+		t.Errorf("unexpected exposition:\n%s\nexpected:\n%s", body, expected)
+	}
+
+	// Scraping does not clear distributions
+	latency.Update(50)
+	exporter.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/metrics", nil))
+	if n := latency.(*StaticDistribution).GetAndClear().NumSamples; n != 1 {
+		t.Errorf("expected scrape to leave 1 pending sample, got %d", n)
+	}
+}
+
+func TestPrometheusExporter_ScheduledWithMultiEmitter(t *testing.T) {
+	fakeClock := clocktest.NewFakeClock(time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC))
+	metrics := NewMetrics()
+	latency := metrics.Distribution("latency", "ms", 100, 2, nil)
+	latency.Update(50)
+
+	// other stands in for the GCP emitter that the exporter shares the schedule with
+	other := &recordingEmitter{}
+	exporter := NewPrometheusExporter(metrics, nil)
+	scheduler := ScheduleMetricsEmit(context.Background(), metrics, 10*time.Second, NewMultiEmitter(other, exporter), &ScheduleOptions{
+		Clock: fakeClock,
+	})
+	fakeClock.WaitForTimers(1)
+	fakeClock.Advance(10 * time.Second)
+	fakeClock.WaitForTimers(1)
+	scheduler.Stop()
+
+	if n := other.emits.Load(); n != 1 {
+		t.Errorf("expected the other emitter to receive 1 emit, got %d", n)
+	}
+	recorder := httptest.NewRecorder()
+	exporter.ServeHTTP(recorder, httptest.NewRequest("GET", "/metrics", nil))
+	if body := recorder.Body.String(); !strings.Contains(body, "latency_count 1\n") {
+		t.Errorf("expected the scheduled snapshot in the histogram, got:\n%s", body)
+	}
+}
+
+func TestPrometheusExporter_GcpMetricsEmitEvery(t *testing.T) {
+	fakeClock := clocktest.NewFakeClock(time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC))
+	// Without a client the GCP emitter only logs an error
+	gcpMetrics := NewGcpMetrics(nil, "", nil, "", &Options{
+		ErrorLogger: log.New(io.Discard, "", 0),
+		Clock:       fakeClock,
+	})
+	gcpMetrics.Distribution("latency", "ms", 100, 2, nil).Update(50)
+
+	exporter := NewPrometheusExporter(gcpMetrics.Metrics, nil)
+	scheduler := gcpMetrics.EmitEvery(context.Background(), 10*time.Second, exporter)
+	fakeClock.WaitForTimers(1)
+	fakeClock.Advance(10 * time.Second)
+	fakeClock.WaitForTimers(1)
+	scheduler.Stop()
+
+	recorder := httptest.NewRecorder()
+	exporter.ServeHTTP(recorder, httptest.NewRequest("GET", "/metrics", nil))
+	if body := recorder.Body.String(); !strings.Contains(body, "latency_count 1\n") {
+		t.Errorf("expected the scheduled snapshot in the histogram, got:\n%s", body)
+	}
+}
+
+func TestPrometheusName(t *testing.T) {
+	for in, expected := range map[string]string{
+		"go/requests": "go_requests",
+		"9lives":      "_lives",
+		"a:b-c.d":     "a:b_c_d",
+	} {
+		if name := prometheusName(in); name != expected {
+			t.Errorf("prometheusName(%q) = %q, expected %q", in, name, expected)
+		}
+	}
+	if !strings.Contains(prometheusLabelName("a:b"), "_") {
+		t.Error("expected colons to be replaced in label names")
+	}
+}
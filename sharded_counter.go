@@ -0,0 +1,72 @@
+package gcpmetrics
+
+import (
+	"math/bits"
+	"math/rand/v2"
+	"runtime"
+	"sync/atomic"
+)
+
+// cacheLineSize is the assumed CPU cache line size used to pad counter cells.
+const cacheLineSize = 64
+
+// counterCell is a single counter stripe padded to occupy a full cache line,
+// so that concurrent updates to neighbouring cells do not contend.
+type counterCell struct {
+	n atomic.Int64
+	_ [cacheLineSize - 8]byte
+}
+
+// counterCells spreads counter increments across padded cells.
+// Each update picks a cell at random using the runtime's per-thread random source,
+// which keeps concurrent writers on different cache lines with high probability.
+type counterCells struct {
+	cells []counterCell
+	mask  uint32
+}
+
+// shardCount returns the number of shards to use for striped metrics:
+// the current GOMAXPROCS, rounded up to a power of two.
+func shardCount() int {
+	return 1 << bits.Len(uint(runtime.GOMAXPROCS(0)-1))
+}
+
+// newCounterCells creates one cell per shard.
+func newCounterCells() *counterCells {
+	n := shardCount()
+	return &counterCells{
+		cells: make([]counterCell, n),
+		mask:  uint32(n - 1),
+	}
+}
+
+// add adds n to a randomly chosen cell.
+func (cc *counterCells) add(n int64) {
+	cc.cells[rand.Uint32()&cc.mask].n.Add(n)
+}
+
+// sum returns the total across all cells. Concurrent updates may or may not be included.
+func (cc *counterCells) sum() int64 {
+	var total int64
+	for i := range cc.cells {
+		total += cc.cells[i].n.Load()
+	}
+	return total
+}
+
+// NewShardedStaticCounter creates a new StaticCounter whose increments are spread across
+// padded per-CPU cells and summed on Value. It trades memory and slower reads for
+// contention-free updates when many goroutines increment the same counter.
+func NewShardedStaticCounter(name string, labels map[string]string) *StaticCounter {
+	return &StaticCounter{
+		Name:   name,
+		Labels: labels,
+		cells:  newCounterCells(),
+	}
+}
+
+// NewShardedDynamicCounter creates a new DynamicCounter whose label combinations are
+// backed by sharded StaticCounter instances. See NewShardedStaticCounter.
+func NewShardedDynamicCounter(name string, staticLabels map[string]string, labelKeys ...string) *DynamicCounter {
+	return newDynamicCounter(name, staticLabels, NewShardedStaticCounter, labelKeys)
+}
@@ -0,0 +1,55 @@
+package gcpmetrics
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestShardedCounter_Concurrent(t *testing.T) {
+	metrics := NewMetrics()
+	static := metrics.ShardedCounter("requests", nil)
+	dynamic := metrics.ShardedCounter("requests_by_status", nil, "status")
+
+	var wg sync.WaitGroup
+	for range 50 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range 1000 {
+				static.Inc()
+				dynamic.Add(2, "200")
+			}
+		}()
+	}
+	wg.Wait()
+
+	if v := static.(*StaticCounter).Value(); v != 50_000 {
+		t.Errorf("expected 50000, got %d", v)
+	}
+	if v := dynamic.(*DynamicCounter).Value("200"); v != 100_000 {
+		t.Errorf("expected 100000, got %d", v)
+	}
+	if len(metrics.Counters) != 1 || len(metrics.DynamicCounters) != 1 {
+		t.Errorf("expected sharded counters to be registered with Metrics")
+	}
+}
+
+func BenchmarkStaticCounter_Inc(b *testing.B) {
+	b.Run("atomic", func(b *testing.B) {
+		counter := NewStaticCounter("requests", nil)
+		b.RunParallel(func(pb *testing.PB) {
+			for pb.Next() {
+				counter.Inc()
+			}
+		})
+	})
+
+	b.Run("sharded", func(b *testing.B) {
+		counter := NewShardedStaticCounter("requests", nil)
+		b.RunParallel(func(pb *testing.PB) {
+			for pb.Next() {
+				counter.Inc()
+			}
+		})
+	})
+}
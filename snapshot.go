@@ -0,0 +1,180 @@
+package gcpmetrics
+
+import (
+	"maps"
+	"slices"
+	"time"
+
+	"github.com/nikolaybotev/go-gcp-metrics/clock"
+	"github.com/nikolaybotev/go-gcp-metrics/iterutil"
+)
+
+// MetricKind describes how the values of a metric relate to time.
+type MetricKind int
+
+const (
+	// MetricKindGauge is an instantaneous measurement.
+	MetricKindGauge MetricKind = iota
+	// MetricKindCumulative is a value accumulated since StartTime, such as a counter.
+	MetricKindCumulative
+	// MetricKindDelta is a value accumulated over the interval since the previous collection,
+	// such as a distribution that is cleared on every collection.
+	MetricKindDelta
+)
+
+// String returns the name of the kind.
+func (k MetricKind) String() string {
+	switch k {
+	case MetricKindGauge:
+		return "GAUGE"
+	case MetricKindCumulative:
+		return "CUMULATIVE"
+	case MetricKindDelta:
+		return "DELTA"
+	default:
+		return "METRIC_KIND_UNSPECIFIED"
+	}
+}
+
+// ValueType describes which value field of a Point is set.
+type ValueType int
+
+const (
+	// ValueTypeInt64 points carry Int64Value.
+	ValueTypeInt64 ValueType = iota
+	// ValueTypeDistribution points carry Distribution and BucketOptions.
+	ValueTypeDistribution
+)
+
+// String returns the name of the value type.
+func (t ValueType) String() string {
+	switch t {
+	case ValueTypeInt64:
+		return "INT64"
+	case ValueTypeDistribution:
+		return "DISTRIBUTION"
+	default:
+		return "VALUE_TYPE_UNSPECIFIED"
+	}
+}
+
+// BucketOptions describes the buckets of a distribution point.
+// Bounds are explicit bucket boundaries: there are len(Bounds)+1 buckets, where the first
+// is the underflow bucket (< Bounds[0]) and the last is the overflow bucket (>= Bounds[len-1]).
+type BucketOptions struct {
+	Bounds []float64
+}
+
+// Point is the value of one time series at collection time.
+type Point struct {
+	Name      string
+	Kind      MetricKind
+	ValueType ValueType
+	Unit      string
+	Labels    map[string]string
+	// StartTime and EndTime bound the interval the value covers.
+	// For gauges StartTime equals EndTime.
+	StartTime time.Time
+	EndTime   time.Time
+	// Int64Value is set for ValueTypeInt64 points.
+	Int64Value int64
+	// Distribution and BucketOptions are set for ValueTypeDistribution points.
+	Distribution  *DistributionBuckets
+	BucketOptions *BucketOptions
+}
+
+// Snapshot is an immutable, backend-agnostic view of the values in a Metrics at one point in time.
+// It shares no state with the Metrics it was collected from. Emitters must treat it as read-only;
+// use Clone to obtain a copy that may be modified.
+type Snapshot struct {
+	Time   time.Time
+	Points []Point
+}
+
+// Collect reads every counter and gauge, clears every distribution, and returns the values as a Snapshot.
+// Distributions are cleared exactly once per Collect, so a snapshot should be collected once per emission
+// and shared between emitters.
+func (m *Metrics) Collect() *Snapshot {
+	m.collectMu.Lock()
+	defer m.collectMu.Unlock()
+
+	now := clock.OrSystem(m.Clock).Now()
+	startTime := m.startTime()
+	deltaStart := m.lastCollect
+	if deltaStart.IsZero() {
+		deltaStart = startTime
+	}
+	m.lastCollect = now
+
+	snapshot := &Snapshot{Time: now}
+
+	for c := range iterutil.CombineMetrics(m.Counters, m.allDynamicCounters()) {
+		snapshot.Points = append(snapshot.Points, Point{
+			Name:       c.Name,
+			Kind:       MetricKindCumulative,
+			ValueType:  ValueTypeInt64,
+			Labels:     maps.Clone(c.Labels),
+			StartTime:  startTime,
+			EndTime:    now,
+			Int64Value: c.Value(),
+		})
+	}
+
+	for g := range iterutil.CombineMetrics(m.Gauges, m.DynamicGauges) {
+		snapshot.Points = append(snapshot.Points, Point{
+			Name:       g.Name,
+			Kind:       MetricKindGauge,
+			ValueType:  ValueTypeInt64,
+			Labels:     maps.Clone(g.Labels),
+			StartTime:  now,
+			EndTime:    now,
+			Int64Value: g.Value(),
+		})
+	}
+
+	for d := range iterutil.CombineMetrics(m.Distributions, m.DynamicDistributions) {
+		snapshot.Points = append(snapshot.Points, Point{
+			Name:          d.Name,
+			Kind:          MetricKindDelta,
+			ValueType:     ValueTypeDistribution,
+			Unit:          d.Unit,
+			Labels:        maps.Clone(d.Labels),
+			StartTime:     deltaStart,
+			EndTime:       now,
+			Distribution:  d.GetAndClear(),
+			BucketOptions: &BucketOptions{Bounds: d.BucketBounds()},
+		})
+	}
+
+	return snapshot
+}
+
+// startTime returns the time the first metric was registered, or the current time if none was.
+// Must be called with collectMu held.
+func (m *Metrics) startTime() time.Time {
+	if m.created.IsZero() {
+		m.created = clock.OrSystem(m.Clock).Now()
+	}
+	return m.created
+}
+
+// Clone returns a deep copy of the snapshot.
+func (s *Snapshot) Clone() *Snapshot {
+	clone := &Snapshot{
+		Time:   s.Time,
+		Points: slices.Clone(s.Points),
+	}
+	for i := range clone.Points {
+		p := &clone.Points[i]
+		p.Labels = maps.Clone(p.Labels)
+		if p.Distribution != nil {
+			dist := *p.Distribution
+			dist.Buckets = slices.Clone(dist.Buckets)
+			p.Distribution = &dist
+		}
+		if p.BucketOptions != nil {
+			p.BucketOptions = &BucketOptions{Bounds: slices.Clone(p.BucketOptions.Bounds)}
+		}
+	}
+	return clone
+}
@@ -0,0 +1,57 @@
+package gcpmetrics
+
+import (
+	"testing"
+	"time"
+
+	"github.com/nikolaybotev/go-gcp-metrics/clock/clocktest"
+)
+
+func TestMetrics_Collect(t *testing.T) {
+	start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	fakeClock := clocktest.NewFakeClock(start)
+	metrics := NewMetrics()
+	metrics.Clock = fakeClock
+
+	counter := metrics.Counter("requests", map[string]string{"env": "prod"})
+	gauge := metrics.Gauge("temperature", nil, "location")
+	dist := metrics.Distribution("latency", "ms", 100, 2, nil)
+	counter.Add(5)
+	gauge.Set(25, "dc1")
+	dist.Update(50)
+	dist.Update(150)
+
+	fakeClock.Advance(10 * time.Second)
+	snapshot := metrics.Collect()
+	if len(snapshot.Points) != 3 {
+		t.Fatalf("expected 3 points, got %d", len(snapshot.Points))
+	}
+
+	c, g, d := snapshot.Points[0], snapshot.Points[1], snapshot.Points[2]
+	if c.Kind != MetricKindCumulative || c.ValueType != ValueTypeInt64 || c.Int64Value != 5 || c.Labels["env"] != "prod" {
+		t.Errorf("unexpected counter point %+v", c)
+	}
+	if !c.StartTime.Equal(start) || !c.EndTime.Equal(start.Add(10*time.Second)) {
+		t.Errorf("unexpected counter interval %v - %v", c.StartTime, c.EndTime)
+	}
+	if g.Kind != MetricKindGauge || g.Int64Value != 25 || g.Labels["location"] != "dc1" || !g.StartTime.Equal(g.EndTime) {
+		t.Errorf("unexpected gauge point %+v", g)
+	}
+	if d.Kind != MetricKindDelta || d.ValueType != ValueTypeDistribution || d.Unit != "ms" || d.Distribution.NumSamples != 2 {
+		t.Errorf("unexpected distribution point %+v", d)
+	}
+	if len(d.BucketOptions.Bounds) != 3 || d.BucketOptions.Bounds[1] != 100 {
+		t.Errorf("unexpected bucket bounds %v", d.BucketOptions.Bounds)
+	}
+
+	// Counters keep their start time; distributions are cleared and start at the previous collection
+	fakeClock.Advance(10 * time.Second)
+	next := metrics.Collect()
+	c, d = next.Points[0], next.Points[2]
+	if !c.StartTime.Equal(start) || c.Int64Value != 5 {
+		t.Errorf("unexpected counter point %+v", c)
+	}
+	if !d.StartTime.Equal(start.Add(10*time.Second)) || d.Distribution.NumSamples != 0 {
+		t.Errorf("unexpected distribution point %+v", d)
+	}
+}
@@ -0,0 +1,257 @@
+package gcpmetrics
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"maps"
+	"math"
+	"net"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// StatsdFlavor selects the StatsD line format.
+type StatsdFlavor int
+
+const (
+	// StatsdPlain writes plain StatsD lines. Labels are folded into the metric name.
+	StatsdPlain StatsdFlavor = iota
+	// StatsdDogStatsD writes DogStatsD lines with labels as tags.
+	StatsdDogStatsD
+)
+
+const (
+	// DefaultStatsdAddress is the default address of the StatsD agent.
+	DefaultStatsdAddress = "127.0.0.1:8125"
+	// DefaultStatsdMaxPacketSize keeps packets within a 1500 byte Ethernet MTU after IP and UDP headers.
+	DefaultStatsdMaxPacketSize = 1432
+)
+
+// StatsdOptions contains optional configuration for StatsdEmitter.
+type StatsdOptions struct {
+	// Address is the host:port of the StatsD agent. Defaults to DefaultStatsdAddress.
+	Address string
+	Flavor  StatsdFlavor
+	// Prefix is prepended to every metric name, separated by a dot. It may itself contain dots.
+	Prefix string
+	// CommonLabels are added to every line, as tags for DogStatsD or name segments for plain StatsD.
+	CommonLabels map[string]string
+	// MaxPacketSize is the largest UDP payload sent. Defaults to DefaultStatsdMaxPacketSize.
+	MaxPacketSize int
+	ErrorLogger   *log.Logger
+}
+
+// StatsdEmitter is a MetricsEmitter that writes metrics to a StatsD or DogStatsD agent over UDP.
+//
+// Counters are sent as the increase since the previous emit (|c), gauges as their current value (|g),
+// and distributions as a summary: the sample count as a counter and the mean and standard deviation
+// as gauges, in <name>.count, <name>.mean and <name>.stddev. Lines are batched into packets of up to
+// MaxPacketSize bytes.
+//
+// Plain StatsD reads a signed gauge value as a change to the current value, so a negative gauge is
+// sent as a reset to 0 followed by the value, in the same packet.
+type StatsdEmitter struct {
+	conn          net.Conn
+	flavor        StatsdFlavor
+	prefix        string
+	commonLabels  map[string]string
+	maxPacketSize int
+	errorLogger   *log.Logger
+	mu            sync.Mutex
+	counters      map[string]int64 // Guarded by mu; last emitted value of the series in the previous snapshot
+}
+
+// NewStatsdEmitter creates a new StatsdEmitter. opts may be nil.
+func NewStatsdEmitter(opts *StatsdOptions) (*StatsdEmitter, error) {
+	// Set defaults if nil
+	if opts == nil {
+		opts = &StatsdOptions{}
+	}
+	address := opts.Address
+	if address == "" {
+		address = DefaultStatsdAddress
+	}
+	maxPacketSize := opts.MaxPacketSize
+	if maxPacketSize <= 0 {
+		maxPacketSize = DefaultStatsdMaxPacketSize
+	}
+	errorLogger := opts.ErrorLogger
+	if errorLogger == nil {
+		errorLogger = log.Default()
+	}
+
+	conn, err := net.Dial("udp", address)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to StatsD agent at %s: %w", address, err)
+	}
+
+	return &StatsdEmitter{
+		conn:          conn,
+		flavor:        opts.Flavor,
+		prefix:        opts.Prefix,
+		commonLabels:  opts.CommonLabels,
+		maxPacketSize: maxPacketSize,
+		errorLogger:   errorLogger,
+		counters:      make(map[string]int64),
+	}, nil
+}
+
+// Close closes the UDP socket.
+func (se *StatsdEmitter) Close() error {
+	return se.conn.Close()
+}
+
+// Emit writes the points in the snapshot as StatsD lines.
+// Distributions without samples and counters that did not change are skipped.
+func (se *StatsdEmitter) Emit(ctx context.Context, snapshot *Snapshot) {
+	se.mu.Lock()
+	defer se.mu.Unlock()
+
+	var packet []byte
+	seen := make(map[string]bool)
+	for _, point := range snapshot.Points {
+		labels := point.Labels
+		if len(se.commonLabels) > 0 {
+			labels = maps.Clone(se.commonLabels)
+			maps.Copy(labels, point.Labels)
+		}
+
+		var lines []string
+		switch {
+		case point.ValueType == ValueTypeDistribution:
+			dist := point.Distribution
+			if dist.NumSamples == 0 {
+				continue
+			}
+			var stdDev float64
+			if dist.NumSamples > 1 {
+				stdDev = math.Sqrt(dist.SumOfSquaredDeviation / float64(dist.NumSamples-1))
+			}
+			lines = []string{
+				se.line(point.Name, ".count", labels, strconv.FormatInt(dist.NumSamples, 10), "c"),
+				se.gaugeLine(point.Name, ".mean", labels, strconv.FormatFloat(dist.Mean, 'f', -1, 64), dist.Mean < 0),
+				se.gaugeLine(point.Name, ".stddev", labels, strconv.FormatFloat(stdDev, 'f', -1, 64), false),
+			}
+		case point.Kind == MetricKindCumulative:
+			key := statsdSeriesKey(point.Name, point.Labels)
+			seen[key] = true
+			delta := point.Int64Value - se.counters[key]
+			if delta < 0 {
+				// The series was deleted and re-created since the previous emit
+				delta = point.Int64Value
+			}
+			se.counters[key] = point.Int64Value
+			if delta == 0 {
+				continue
+			}
+			lines = []string{se.line(point.Name, "", labels, strconv.FormatInt(delta, 10), "c")}
+		default:
+			lines = []string{se.gaugeLine(point.Name, "", labels, strconv.FormatInt(point.Int64Value, 10), point.Int64Value < 0)}
+		}
+
+		for _, line := range lines {
+			if len(packet) > 0 && len(packet)+1+len(line) > se.maxPacketSize {
+				se.send(packet)
+				packet = packet[:0]
+			}
+			if len(packet) > 0 {
+				packet = append(packet, '\n')
+			}
+			packet = append(packet, line...)
+		}
+	}
+	if len(packet) > 0 {
+		se.send(packet)
+	}
+
+	// Forget series that are no longer collected, so that the map does not grow with every label set
+	maps.DeleteFunc(se.counters, func(key string, _ int64) bool {
+		return !seen[key]
+	})
+}
+
+// send writes one packet to the agent.
+func (se *StatsdEmitter) send(packet []byte) {
+	if _, err := se.conn.Write(packet); err != nil {
+		se.errorLogger.Printf("failed to send StatsD packet: %v", err)
+	}
+}
+
+// gaugeLine formats a gauge line. For plain StatsD, a negative value is preceded by a reset to 0, since a
+// leading sign would otherwise be applied as a change to the current value. Both lines are returned as one
+// string, so that they are sent in the same packet.
+func (se *StatsdEmitter) gaugeLine(name, suffix string, labels map[string]string, value string, negative bool) string {
+	line := se.line(name, suffix, labels, value, "g")
+	if negative && se.flavor == StatsdPlain {
+		return se.line(name, suffix, labels, "0", "g") + "\n" + line
+	}
+	return line
+}
+
+// line formats one StatsD line. The suffix is appended to the sanitized name.
+func (se *StatsdEmitter) line(name, suffix string, labels map[string]string, value, typ string) string {
+	var b strings.Builder
+	if se.prefix != "" {
+		b.WriteString(se.prefix)
+		b.WriteByte('.')
+	}
+	b.WriteString(statsdName(name))
+	b.WriteString(suffix)
+	keys := slices.Sorted(maps.Keys(labels))
+	if se.flavor == StatsdPlain {
+		for _, k := range keys {
+			b.WriteByte('.')
+			b.WriteString(statsdName(k))
+			b.WriteByte('_')
+			b.WriteString(statsdName(labels[k]))
+		}
+	}
+	b.WriteByte(':')
+	b.WriteString(value)
+	b.WriteByte('|')
+	b.WriteString(typ)
+	if se.flavor == StatsdDogStatsD && len(keys) > 0 {
+		b.WriteString("|#")
+		for i, k := range keys {
+			if i > 0 {
+				b.WriteByte(',')
+			}
+			b.WriteString(statsdTag(k))
+			b.WriteByte(':')
+			b.WriteString(statsdTag(labels[k]))
+		}
+	}
+	return b.String()
+}
+
+// statsdNameReplacer replaces characters that delimit fields of a StatsD line or segments of a name.
+var statsdNameReplacer = strings.NewReplacer(":", "_", "|", "_", "@", "_", "#", "_", ",", "_", ".", "_", "/", "_", " ", "_", "\n", "_")
+
+// statsdName sanitizes one segment of a StatsD metric name.
+func statsdName(s string) string {
+	return statsdNameReplacer.Replace(s)
+}
+
+// statsdTagReplacer replaces characters that delimit fields or tags of a DogStatsD line.
+var statsdTagReplacer = strings.NewReplacer("|", "_", ",", "_", "#", "_", "\n", "_")
+
+// statsdTag sanitizes a DogStatsD tag key or value. Colons are kept, since they are allowed in values.
+func statsdTag(s string) string {
+	return statsdTagReplacer.Replace(s)
+}
+
+// statsdSeriesKey identifies a counter series by name and labels.
+func statsdSeriesKey(name string, labels map[string]string) string {
+	var b strings.Builder
+	b.WriteString(name)
+	for _, k := range slices.Sorted(maps.Keys(labels)) {
+		b.WriteByte(0)
+		b.WriteString(k)
+		b.WriteByte(0)
+		b.WriteString(labels[k])
+	}
+	return b.String()
+}
@@ -0,0 +1,167 @@
+package gcpmetrics
+
+import (
+	"context"
+	"errors"
+	"net"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// listenStatsd starts a local UDP listener and returns it with a function that reads the lines of n packets.
+func listenStatsd(t *testing.T) (*net.UDPConn, func(n int) []string) {
+	t.Helper()
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	read := func(n int) []string {
+		var lines []string
+		buf := make([]byte, 65536)
+		for range n {
+			conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+			size, err := conn.Read(buf)
+			if err != nil {
+				t.Fatalf("failed to read packet: %v", err)
+			}
+			lines = append(lines, strings.Split(string(buf[:size]), "\n")...)
+		}
+		return lines
+	}
+	return conn, read
+}
+
+func TestStatsdEmitter_DogStatsD(t *testing.T) {
+	conn, read := listenStatsd(t)
+	emitter, err := NewStatsdEmitter(&StatsdOptions{
+		Address:      conn.LocalAddr().String(),
+		Flavor:       StatsdDogStatsD,
+		Prefix:       "app",
+		CommonLabels: map[string]string{"env": "test"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer emitter.Close()
+
+	metrics := NewMetrics()
+	requests := metrics.Counter("requests", nil, "status")
+	metrics.Gauge("temperature", nil).Set(25)
+	latency := metrics.Distribution("latency", "ms", 100, 2, nil)
+	requests.Add(3, "200")
+	latency.Update(10)
+	latency.Update(30)
+
+	emitter.Emit(context.Background(), metrics.Collect())
+	lines := read(1)
+	slices.Sort(lines)
+	expected := []string{
+		"app.latency.count:2|c|#env:test",
+		"app.latency.mean:20|g|#env:test",
+		"app.latency.stddev:14.142135623730951|g|#env:test",
+		"app.requests:3|c|#env:test,status:200",
+		"app.temperature:25|g|#env:test",
+	}
+	if !slices.Equal(lines, expected) {
+		t.Errorf("expected %q, got %q", expected, lines)
+	}
+
+	// Counters are sent as deltas
+	requests.Add(2, "200")
+	emitter.Emit(context.Background(), metrics.Collect())
+	lines = read(1)
+	expected = []string{"app.requests:2|c|#env:test,status:200", "app.temperature:25|g|#env:test"}
+	if !slices.Equal(lines, expected) {
+		t.Errorf("expected %q, got %q", expected, lines)
+	}
+}
+
+func TestStatsdEmitter_PlainBatching(t *testing.T) {
+	conn, read := listenStatsd(t)
+	emitter, err := NewStatsdEmitter(&StatsdOptions{
+		Address:       conn.LocalAddr().String(),
+		MaxPacketSize: 64,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer emitter.Close()
+
+	metrics := NewMetrics()
+	gauge := metrics.Gauge("queue.depth", nil, "queue")
+	for _, queue := range []string{"a", "b", "c", "d", "e", "f"} {
+		gauge.Set(1, queue)
+	}
+
+	// Each line is 23 bytes, so two fit in a 64 byte packet
+	emitter.Emit(context.Background(), metrics.Collect())
+	lines := read(3)
+	slices.Sort(lines)
+	if len(lines) != 6 || lines[0] != "queue_depth.queue_a:1|g" {
+		t.Errorf("unexpected lines %q", lines)
+	}
+}
+
+func TestStatsdEmitter_PlainNegativeGauge(t *testing.T) {
+	conn, read := listenStatsd(t)
+	emitter, err := NewStatsdEmitter(&StatsdOptions{Address: conn.LocalAddr().String()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer emitter.Close()
+
+	metrics := NewMetrics()
+	metrics.Gauge("balance", nil).Set(-5)
+	latency := metrics.Distribution("offset", "ms", 10, 2, nil)
+	latency.Update(-4)
+	latency.Update(-2)
+
+	emitter.Emit(context.Background(), metrics.Collect())
+	expected := []string{
+		"balance:0|g",
+		"balance:-5|g",
+		"offset.count:2|c",
+		"offset.mean:0|g",
+		"offset.mean:-3|g",
+		"offset.stddev:1.4142135623730951|g",
+	}
+	if lines := read(1); !slices.Equal(lines, expected) {
+		t.Errorf("expected %q, got %q", expected, lines)
+	}
+}
+
+func TestStatsdEmitter_ForgetsRemovedCounters(t *testing.T) {
+	conn, read := listenStatsd(t)
+	emitter, err := NewStatsdEmitter(&StatsdOptions{Address: conn.LocalAddr().String()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer emitter.Close()
+
+	first := NewMetrics()
+	first.Counter("requests", nil, "status").Add(3, "200")
+	emitter.Emit(context.Background(), first.Collect())
+	read(1)
+
+	second := NewMetrics()
+	second.Counter("requests", nil, "status").Add(1, "500")
+	emitter.Emit(context.Background(), second.Collect())
+	read(1)
+
+	emitter.mu.Lock()
+	defer emitter.mu.Unlock()
+	if _, ok := emitter.counters[statsdSeriesKey("requests", map[string]string{"status": "200"})]; ok || len(emitter.counters) != 1 {
+		t.Errorf("expected only the collected series to be remembered, got %d series", len(emitter.counters))
+	}
+}
+
+func TestNewStatsdEmitter_DialError(t *testing.T) {
+	_, err := NewStatsdEmitter(&StatsdOptions{Address: "127.0.0.1:99999"})
+	var opErr *net.OpError
+	if !errors.As(err, &opErr) {
+		t.Errorf("expected the dial error to be wrapped, got %v", err)
+	}
+}